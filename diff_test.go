@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns the lines 1 to n, with the ones in changed replaced.
+func numbered(n int, changed map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if line, ok := changed[i]; ok {
+			b.WriteString(line + "\n")
+		} else {
+			fmt.Fprintf(&b, "%d\n", i)
+		}
+	}
+	return b.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"same", "a\nb\n", "a\nb\n", ""},
+		{"context",
+			numbered(10, nil), numbered(10, map[int]string{5: "five"}),
+			"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"},
+		{"nearby changes merged",
+			numbered(12, nil), numbered(12, map[int]string{2: "two", 8: "eight"}),
+			"@@ -1,11 +1,11 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n 11\n"},
+		{"distant changes split",
+			numbered(14, nil), numbered(14, map[int]string{2: "two", 11: "eleven"}),
+			"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -8,7 +8,7 @@\n 8\n 9\n 10\n-11\n+eleven\n 12\n 13\n 14\n"},
+		{"deletion", "a\nb\nc\n", "a\nc\n", "@@ -1,3 +1,2 @@\n a\n-b\n c\n"},
+		{"empty file", "", "a\n", "@@ -0,0 +1 @@\n+a\n"},
+		{"no newline at end",
+			"a\nb", "a\nc",
+			"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"},
+		{"newline removed at end",
+			"a\nb\n", "a\nb",
+			"@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n"},
+	} {
+		want := test.want
+		if want != "" {
+			want = "--- old\n+++ new\n" + want
+		}
+		diff := unifiedDiff("old", "new", []byte(test.old), []byte(test.new))
+		if string(diff) != want {
+			t.Errorf("%s: expected diff\n%s\ngot\n%s", test.name, want, diff)
+		}
+	}
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func init() {
-	extpoints.Register(new(exampleExtension), "")
+	extpoints.RegisterExtension(new(exampleExtension), "")
 }
 
 type exampleExtension struct{}
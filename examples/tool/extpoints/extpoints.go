@@ -2,175 +2,81 @@
 package extpoints
 
 import (
-	"reflect"
-	"sync"
+	"context"
+
+	"github.com/progrium/go-extpoints/extpoints"
 )
 
-var registry = struct {
-	sync.Mutex
-	extpoints map[string]*extensionPoint
-}{
-	extpoints: make(map[string]*extensionPoint),
-}
+var extRegistry = extpoints.NewRegistry()
 
-type extensionPoint struct {
-	sync.Mutex
-	iface      reflect.Type
-	components map[string]interface{}
+// Top level registration
+
+func RegisterExtension(extension interface{}, name string, opts ...extpoints.Option) []string {
+	return extRegistry.RegisterExtension(extension, name, opts...)
 }
 
-func newExtensionPoint(iface interface{}) *extensionPoint {
-	ep := &extensionPoint{
-		iface:      reflect.TypeOf(iface).Elem(),
-		components: make(map[string]interface{}),
-	}
-	registry.Lock()
-	defer registry.Unlock()
-	registry.extpoints[ep.iface.Name()] = ep
-	return ep
+func TryRegisterExtension(extension interface{}, name string, opts ...extpoints.Option) ([]string, error) {
+	return extRegistry.TryRegisterExtension(extension, name, opts...)
 }
 
-func (ep *extensionPoint) lookup(name string) (ext interface{}, ok bool) {
-	ep.Lock()
-	defer ep.Unlock()
-	ext, ok = ep.components[name]
-	return
+func MustRegisterExtension(extension interface{}, name string, opts ...extpoints.Option) []string {
+	return extRegistry.MustRegisterExtension(extension, name, opts...)
 }
 
-func (ep *extensionPoint) all() map[string]interface{} {
-	ep.Lock()
-	defer ep.Unlock()
-	all := make(map[string]interface{})
-	for k, v := range ep.components {
-		all[k] = v
-	}
-	return all
+func UnregisterExtension(name string) []string {
+	return extRegistry.UnregisterExtension(name)
 }
 
-func (ep *extensionPoint) register(component interface{}, name string) bool {
-	ep.Lock()
-	defer ep.Unlock()
-	if name == "" {
-		name = reflect.TypeOf(component).Elem().Name()
-	}
-	_, exists := ep.components[name]
-	if exists {
-		return false
-	}
-	ep.components[name] = component
-	return true
+func StartExtensions(ctx context.Context) error {
+	return extRegistry.Start(ctx)
 }
 
-func (ep *extensionPoint) unregister(name string) bool {
-	ep.Lock()
-	defer ep.Unlock()
-	_, exists := ep.components[name]
-	if !exists {
-		return false
-	}
-	delete(ep.components, name)
-	return true
+func StopExtensions(ctx context.Context) error {
+	return extRegistry.Stop(ctx)
 }
 
-func implements(component interface{}) []string {
-	var ifaces []string
-	for name, ep := range registry.extpoints {
-		if reflect.TypeOf(component).Implements(ep.iface) {
-			ifaces = append(ifaces, name)
-		}
-	}
-	return ifaces
+// ExtensionRegistry returns the registry of the extension points, for
+// watching them all, sealing them or taking a snapshot of them.
+func ExtensionRegistry() *extpoints.Registry {
+	return extRegistry
 }
 
-func Register(component interface{}, name string) []string {
-	registry.Lock()
-	defer registry.Unlock()
-	var ifaces []string
-	for _, iface := range implements(component) {
-		if ok := registry.extpoints[iface].register(component, name); ok {
-			ifaces = append(ifaces, iface)
-		}
-	}
-	return ifaces
+// ExtensionPoints is a set of the extension points with a registry of its
+// own, isolated from the package level ones, for tests and other uses
+// needing their own sets of extensions.
+type ExtensionPoints struct {
+	Registry              *extpoints.Registry
+	LifecycleParticipants *lifecycleParticipantExt
+	CommandProviders      *commandProviderExt
 }
 
-func Unregister(name string) []string {
-	registry.Lock()
-	defer registry.Unlock()
-	var ifaces []string
-	for iface, extpoint := range registry.extpoints {
-		if ok := extpoint.unregister(name); ok {
-			ifaces = append(ifaces, iface)
-		}
+// NewExtensionPoints returns a new set of the extension points with an
+// empty registry.
+func NewExtensionPoints() *ExtensionPoints {
+	r := extpoints.NewRegistry()
+	return &ExtensionPoints{
+		Registry:              r,
+		LifecycleParticipants: &lifecycleParticipantExt{extpoints.NewPoint[LifecycleParticipant](r, "LifecycleParticipant", "LifecycleParticipants")},
+		CommandProviders:      &commandProviderExt{extpoints.NewPoint[CommandProvider](r, "CommandProvider", "CommandProviders")},
 	}
-	return ifaces
 }
 
 // LifecycleParticipant
 
 var LifecycleParticipants = &lifecycleParticipantExt{
-	newExtensionPoint(new(LifecycleParticipant)),
+	extpoints.NewPoint[LifecycleParticipant](extRegistry, "LifecycleParticipant", "LifecycleParticipants"),
 }
 
 type lifecycleParticipantExt struct {
-	*extensionPoint
-}
-
-func (ep *lifecycleParticipantExt) Unregister(name string) bool {
-	return ep.unregister(name)
-}
-
-func (ep *lifecycleParticipantExt) Register(component LifecycleParticipant, name string) bool {
-	return ep.register(component, name)
-}
-
-func (ep *lifecycleParticipantExt) Lookup(name string) (LifecycleParticipant, bool) {
-	ext, ok := ep.lookup(name)
-	if !ok {
-		return nil, ok
-	}
-	return ext.(LifecycleParticipant), ok
-}
-
-func (ep *lifecycleParticipantExt) All() map[string]LifecycleParticipant {
-	all := make(map[string]LifecycleParticipant)
-	for k, v := range ep.all() {
-		all[k] = v.(LifecycleParticipant)
-	}
-	return all
+	*extpoints.Point[LifecycleParticipant]
 }
 
 // CommandProvider
 
 var CommandProviders = &commandProviderExt{
-	newExtensionPoint(new(CommandProvider)),
+	extpoints.NewPoint[CommandProvider](extRegistry, "CommandProvider", "CommandProviders"),
 }
 
 type commandProviderExt struct {
-	*extensionPoint
-}
-
-func (ep *commandProviderExt) Unregister(name string) bool {
-	return ep.unregister(name)
-}
-
-func (ep *commandProviderExt) Register(component CommandProvider, name string) bool {
-	return ep.register(component, name)
+	*extpoints.Point[CommandProvider]
 }
-
-func (ep *commandProviderExt) Lookup(name string) (CommandProvider, bool) {
-	ext, ok := ep.lookup(name)
-	if !ok {
-		return nil, ok
-	}
-	return ext.(CommandProvider), ok
-}
-
-func (ep *commandProviderExt) All() map[string]CommandProvider {
-	all := make(map[string]CommandProvider)
-	for k, v := range ep.all() {
-		all[k] = v.(CommandProvider)
-	}
-	return all
-}
-
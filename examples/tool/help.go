@@ -119,11 +119,6 @@ func listRec(w io.Writer, a ...interface{}) {
 	}
 }
 
-func usage() {
-	printUsage()
-	os.Exit(2)
-}
-
 type commandList []*types.Command
 
 func (cl commandList) Len() int           { return len(cl) }
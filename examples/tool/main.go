@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -25,14 +26,25 @@ func assert(err error) {
 func main() {
 	log.SetFlags(0)
 
-	for _, provider := range commandProviders.All() {
+	// extensions implementing Init, Start or Stop are driven here, and
+	// stopped before exiting with the code of the command
+	ctx := context.Background()
+	assert(extpoints.StartExtensions(ctx))
+	code := run()
+	assert(extpoints.StopExtensions(ctx))
+	os.Exit(code)
+}
+
+func run() int {
+	for _, provider := range commandProviders.Ordered() {
 		commands = append(commands, provider.Commands()...)
 	}
 
 	// make sure command is specified
 	args := os.Args[1:]
 	if len(args) < 1 {
-		usage()
+		printUsage()
+		return 2
 	}
 
 	for _, cmd := range commands {
@@ -41,21 +53,22 @@ func main() {
 				cmd.PrintUsage()
 			}
 			if err := cmd.Flag.Parse(args[1:]); err != nil {
-				os.Exit(2)
+				return 2
 			}
-			for _, participant := range lifecycleParticipant.All() {
+			for _, participant := range lifecycleParticipant.Ordered() {
 				if err := participant.CommandStart(cmd.Name()); err != nil {
-					os.Exit(3)
+					return 3
 				}
 			}
 			cmd.Run(cmd, cmd.Flag.Args())
-			for _, participant := range lifecycleParticipant.All() {
+			for _, participant := range lifecycleParticipant.Ordered() {
 				participant.CommandFinish(cmd.Name())
 			}
-			return
+			return 0
 		}
 	}
 
 	fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
-	usage()
+	printUsage()
+	return 2
 }
@@ -0,0 +1,258 @@
+package extpoints
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Requirer is implemented by extensions that depend on other extensions
+// without having them injected. Each requirement is written like an
+// extpoint struct tag.
+type Requirer interface {
+	Requires() []string
+}
+
+// errPerCall is the error of a dependency on an extension created per
+// call, which is registered but can't be injected.
+var errPerCall = errors.New("extension is created per call and can't be injected")
+
+// dependency is a parsed extpoint struct tag or requirement, like
+// "StorageDriver/local,optional".
+type dependency struct {
+	point    string
+	name     string
+	optional bool
+}
+
+func parseDependency(spec string) (dependency, error) {
+	ref, opts, _ := strings.Cut(spec, ",")
+	var dep dependency
+	dep.point, dep.name, _ = strings.Cut(ref, "/")
+	if dep.point == "" {
+		return dep, fmt.Errorf("no extension point in %q", spec)
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "":
+		case "optional":
+			dep.optional = true
+		default:
+			return dep, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	return dep, nil
+}
+
+// requirements returns the result of the Requires method of each of
+// members that has one. The registry mustn't be locked, as the methods
+// may use it.
+func requirements(members []member) [][]string {
+	requires := make([][]string, len(members))
+	for i, m := range members {
+		if requirer, ok := m.extension.(Requirer); ok {
+			requires[i] = requirer.Requires()
+		}
+	}
+	return requires
+}
+
+// resolve injects the dependencies of members and returns them ordered so
+// each comes after the extensions it depends on, keeping their order
+// otherwise. requires[i] are the requirements of members[i]. The registry
+// must be locked.
+func (r *Registry) resolve(members []member, requires [][]string) ([]member, error) {
+	// deps[i] are the members that members[i] depends on
+	deps := make([][]int, len(members))
+	for i, m := range members {
+		found, err := r.inject(m, requires[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range found {
+			if j := indexMember(members, f); j >= 0 && j != i {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	ordered := make([]member, 0, len(members))
+	done := make([]bool, len(members))
+	for len(ordered) < len(members) {
+		next := -1
+		for i := range members {
+			if !done[i] && allDone(done, deps[i]) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var cycle []string
+			for i, m := range members {
+				if !done[i] {
+					cycle = append(cycle, m.point+"/"+m.name)
+				}
+			}
+			return nil, &CycleError{Names: cycle}
+		}
+		done[next] = true
+		ordered = append(ordered, members[next])
+	}
+	return ordered, nil
+}
+
+// inject sets the fields of m tagged with extpoint, returning the
+// extensions m depends on through them and requires.
+func (r *Registry) inject(m member, requires []string) ([]member, error) {
+	var found []member
+	for _, spec := range requires {
+		deps, err := r.satisfy(m, spec, nil, reflect.Value{})
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, deps...)
+	}
+
+	v := reflect.ValueOf(m.extension)
+	if v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct {
+		v = v.Elem()
+	} else if v.Kind() != reflect.Struct {
+		return found, nil
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		spec, ok := field.Tag.Lookup("extpoint")
+		if !ok {
+			continue
+		}
+		if !field.IsExported() || !v.Field(i).CanSet() {
+			err := fmt.Errorf("field %s can't be set; it needs to be exported, and the extension a pointer", field.Name)
+			return nil, &DependencyError{Point: m.point, Name: m.name, Dependency: spec, Err: err}
+		}
+		deps, err := r.satisfy(m, spec, field.Type, v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, deps...)
+	}
+	return found, nil
+}
+
+// satisfy looks up a dependency of m, setting field to it if it's valid.
+func (r *Registry) satisfy(m member, spec string, typ reflect.Type, field reflect.Value) ([]member, error) {
+	dependencyError := func(err error) error {
+		return &DependencyError{Point: m.point, Name: m.name, Dependency: spec, Err: err}
+	}
+	dep, err := parseDependency(spec)
+	if err != nil {
+		return nil, dependencyError(err)
+	}
+	ep, ok := r.lookup(dep.point)
+	if !ok {
+		if dep.optional {
+			return nil, nil
+		}
+		return nil, dependencyError(ErrMissingDependency)
+	}
+	value, found, err := ep.dependency(typ, dep.name)
+	if err != nil {
+		return nil, dependencyError(err)
+	}
+	if dep.name != "" && len(found) == 0 {
+		if dep.optional {
+			return nil, nil
+		}
+		return nil, dependencyError(ErrMissingDependency)
+	}
+	if field.IsValid() {
+		field.Set(value)
+	}
+	return found, nil
+}
+
+// dependency returns the value to inject into a field of type typ for a
+// dependency on ep, or on its extension name if name isn't "", along with
+// the extensions depended on. If typ is nil, only the extensions are
+// returned. A dependency on a whole point can be injected as a slice of
+// its extensions in the order returned by Sorted, a map of them by name,
+// or the point itself, which isn't a dependency on its extensions.
+// Extensions created by a factory on every lookup can't be injected, and
+// naming one fails with errPerCall.
+func (ep *Point[T]) dependency(typ reflect.Type, name string) (reflect.Value, []member, error) {
+	if name == "" && typ == reflect.TypeOf(ep) {
+		return reflect.ValueOf(ep), nil, nil
+	}
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if reg, ok := ep.extensions[name]; ok && reg.factory != nil && reg.perCall {
+		return reflect.Value{}, nil, errPerCall
+	}
+	names := []string{name}
+	if name == "" {
+		var err error
+		if names, err = ep.sortedNames(); err != nil {
+			return reflect.Value{}, nil, err
+		}
+	}
+	found := make([]member, 0, len(names))
+	values := make([]reflect.Value, 0, len(names))
+	for _, name := range names {
+		reg, ok := ep.extensions[name]
+		if !ok {
+			continue
+		}
+		extension, ok := reg.current()
+		if !ok {
+			continue
+		}
+		found = append(found, member{ep.name, name, extension})
+		values = append(values, reflect.ValueOf(&extension).Elem())
+	}
+	if name != "" && len(found) == 0 {
+		return reflect.Value{}, nil, nil
+	}
+	if typ == nil {
+		return reflect.Value{}, found, nil
+	}
+
+	switch {
+	case name != "" && ep.iface.AssignableTo(typ):
+		return values[0], found, nil
+	case name == "" && typ.Kind() == reflect.Slice && ep.iface.AssignableTo(typ.Elem()):
+		return reflect.Append(reflect.MakeSlice(typ, 0, len(values)), values...), found, nil
+	case name == "" && typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String &&
+		ep.iface.AssignableTo(typ.Elem()):
+		m := reflect.MakeMapWithSize(typ, len(values))
+		for i, f := range found {
+			m.SetMapIndex(reflect.ValueOf(f.name).Convert(typ.Key()), values[i])
+		}
+		return m, found, nil
+	}
+	return reflect.Value{}, nil, fmt.Errorf("a field of type %s can't hold it", typ)
+}
+
+// indexMember returns the index of the member of members that's the
+// extension f, or -1.
+func indexMember(members []member, f member) int {
+	for i, m := range members {
+		if m.point == f.point && m.name == f.name {
+			return i
+		}
+	}
+	for i, m := range members {
+		if sameExtension(m.extension, f.extension) {
+			return i
+		}
+	}
+	return -1
+}
+
+func allDone(done []bool, deps []int) bool {
+	for _, i := range deps {
+		if !done[i] {
+			return false
+		}
+	}
+	return true
+}
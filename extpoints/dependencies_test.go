@@ -0,0 +1,137 @@
+package extpoints
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type storage interface {
+	Store() string
+}
+
+type driver struct {
+	name string
+	log  *[]string
+}
+
+func (d *driver) Store() string {
+	return d.name
+}
+
+func (d *driver) Start(ctx context.Context) error {
+	*d.log = append(*d.log, d.name)
+	return nil
+}
+
+type server struct {
+	Drivers []storage          `extpoint:"storages"`
+	ByName  map[string]storage `extpoint:"storage"`
+	Local   storage            `extpoint:"storage/local"`
+	Cache   storage            `extpoint:"storage/cache,optional"`
+	Point   *Point[storage]    `extpoint:"storage"`
+	log     *[]string
+}
+
+func (s *server) Start(ctx context.Context) error {
+	*s.log = append(*s.log, "server")
+	return nil
+}
+
+type requirer struct {
+	requires []string
+}
+
+func (r *requirer) Start(ctx context.Context) error {
+	return nil
+}
+
+func (r *requirer) Requires() []string {
+	return r.requires
+}
+
+func TestDependencies(t *testing.T) {
+	registry := NewRegistry()
+	services := NewPoint[Starter](registry, "service")
+	storages := NewPoint[storage](registry, "storage", "storages")
+
+	var log []string
+	srv := &server{log: &log}
+	services.Register(srv, "server")
+	storages.Register(&driver{"local", &log}, "local")
+	storages.Register(&driver{"s3", &log}, "s3", Priority(1))
+
+	if err := registry.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(log, ", "); got != "s3, local, server" {
+		t.Errorf("expected dependencies started first, got %q", got)
+	}
+	if len(srv.Drivers) != 2 || srv.Drivers[0].Store() != "s3" || len(srv.ByName) != 2 ||
+		srv.Local.Store() != "local" || srv.Cache != nil || srv.Point != storages {
+		t.Errorf("unexpected injected dependencies: %+v", srv)
+	}
+	registry.Stop(context.Background())
+
+	storages.RegisterFactory("lazy", func() (storage, error) {
+		return &driver{"lazy", &log}, nil
+	}, PerCall())
+	for _, test := range []struct {
+		name string
+		ext  interface{}
+		want string
+	}{
+		{"missing", &requirer{[]string{"storage/missing"}},
+			`service extension "missing" depends on storage/missing: required extension isn't registered`},
+		{"missing point", &requirer{[]string{"queue"}},
+			`service extension "missing point" depends on queue: required extension isn't registered`},
+		{"bad option", &requirer{[]string{"storage,required"}},
+			`service extension "bad option" depends on storage,required: unknown option "required"`},
+		{"per call", &requirer{[]string{"storage/lazy"}},
+			`service extension "per call" depends on storage/lazy: extension is created per call and can't be injected`},
+		{"bad field", &struct {
+			requirer
+			Local []storage `extpoint:"storage/local"`
+		}{},
+			`service extension "bad field" depends on storage/local: a field of type []extpoints.storage can't hold it`},
+	} {
+		services.Register(test.ext.(Starter), test.name)
+		err := registry.Start(context.Background())
+		if err == nil || err.Error() != "extpoints: "+test.want {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.want, err)
+		}
+		services.Unregister(test.name)
+	}
+	storages.Unregister("lazy")
+
+	services.Register(&requirer{[]string{"service/b"}}, "a")
+	services.Register(&requirer{[]string{"service/a"}}, "b")
+	var cycleErr *CycleError
+	if err := registry.Start(context.Background()); !errors.As(err, &cycleErr) ||
+		strings.Join(cycleErr.Names, " ") != "service/a service/b" {
+		t.Errorf("expected dependency cycle, got %v", err)
+	}
+	services.Unregister("a")
+	services.Unregister("b")
+
+	// Requires can use the registry without deadlocking Start
+	services.Register(&lookupRequirer{storages}, "lookup")
+	if err := registry.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	registry.Stop(context.Background())
+}
+
+type lookupRequirer struct {
+	storages *Point[storage]
+}
+
+func (r *lookupRequirer) Start(ctx context.Context) error {
+	return nil
+}
+
+func (r *lookupRequirer) Requires() []string {
+	r.storages.registry.UnregisterExtension("missing")
+	return []string{"storage/" + r.storages.Names()[0]}
+}
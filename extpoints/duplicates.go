@@ -0,0 +1,62 @@
+package extpoints
+
+import (
+	"errors"
+	"strconv"
+)
+
+// DuplicatePolicy says what a Point does when an extension is registered
+// under a name that's already taken.
+type DuplicatePolicy int
+
+const (
+	// DuplicateReject keeps the extension that was registered first and
+	// fails the new registration with ErrDuplicate. It's the default.
+	DuplicateReject DuplicatePolicy = iota
+	// DuplicateReplaceWarn replaces the registered extension and logs a
+	// warning, so overrides are visible in the program's output.
+	DuplicateReplaceWarn
+	// DuplicateReplace replaces the registered extension without a word.
+	DuplicateReplace
+	// DuplicateError treats a duplicate as a programming error, making
+	// Register and RegisterExtension panic with the *RegisterError wrapping
+	// ErrDuplicate, once the extension has been registered with any other
+	// points. Methods returning errors, like TryRegister, return it.
+	DuplicateError
+)
+
+func (p DuplicatePolicy) String() string {
+	switch p {
+	case DuplicateReject:
+		return "reject"
+	case DuplicateReplaceWarn:
+		return "replace-with-warning"
+	case DuplicateReplace:
+		return "replace"
+	case DuplicateError:
+		return "error"
+	}
+	return "DuplicatePolicy(" + strconv.Itoa(int(p)) + ")"
+}
+
+// panicDuplicate panics with err if it is, or joins, a *RegisterError for
+// a duplicate under DuplicateError.
+func panicDuplicate(err error) {
+	if isFatal(err) {
+		panic(err)
+	}
+}
+
+func isFatal(err error) bool {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		for _, err := range joined.Unwrap() {
+			if isFatal(err) {
+				return true
+			}
+		}
+		return false
+	}
+	var registerErr *RegisterError
+	return errors.As(err, &registerErr) && registerErr.fatal
+}
@@ -0,0 +1,107 @@
+package extpoints
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors wrapped by a *RegisterError, telling why an extension wasn't
+// registered. Test for them with errors.Is.
+var (
+	ErrDuplicate      = errors.New("an extension is already registered under the name")
+	ErrNotImplemented = errors.New("extension doesn't implement the extension type")
+	ErrSealed         = errors.New("extension point is sealed")
+	ErrInvalidName    = errors.New("no name given and none could be derived from the extension")
+)
+
+// ErrMissingDependency is wrapped by a *DependencyError when a required
+// extension point or extension isn't registered.
+var ErrMissingDependency = errors.New("required extension isn't registered")
+
+// RegisterError is returned when an extension can't be registered.
+type RegisterError struct {
+	// Point is the extension point, or "" if the extension implements none
+	// of the extension points in a registry.
+	Point string
+	Name  string
+	// Source is where the extension was registered from, and Existing is
+	// where the extension already registered under the name was, if Err
+	// is ErrDuplicate.
+	Source   Source
+	Existing Source
+	Err      error
+
+	// fatal is set for a duplicate under DuplicateError.
+	fatal bool
+}
+
+func (e *RegisterError) Error() string {
+	if e.Err == ErrDuplicate {
+		return fmt.Sprintf("extpoints: %s extension %q registered by %s, rejected from %s",
+			e.Point, e.Name, e.Existing, e.Source)
+	}
+	if e.Point == "" {
+		return fmt.Sprintf("extpoints: can't register %q with any extension point: %s", e.Name, e.Err)
+	}
+	return fmt.Sprintf("extpoints: can't register %q with %s: %s", e.Name, e.Point, e.Err)
+}
+
+func (e *RegisterError) Unwrap() error {
+	return e.Err
+}
+
+// CycleError is returned by Sorted when the Before and After constraints
+// of extensions can't all be satisfied, and by Start when the dependencies
+// of extensions can't.
+type CycleError struct {
+	// Point is "" for a dependency cycle, which can span extension points.
+	Point string
+	// Names are the extensions that are part of, or ordered after, a cycle.
+	// For a dependency cycle, they're qualified like Point/name.
+	Names []string
+}
+
+func (e *CycleError) Error() string {
+	if e.Point == "" {
+		return "extpoints: dependencies of extensions form a cycle: " + strings.Join(e.Names, ", ")
+	}
+	return fmt.Sprintf("extpoints: ordering constraints of %s extensions form a cycle: %s",
+		e.Point, strings.Join(e.Names, ", "))
+}
+
+// LifecycleError is returned when an extension fails to initialize, start
+// or stop, or its factory fails to create it.
+type LifecycleError struct {
+	// Op is "initialize", "start", "stop" or "create".
+	Op    string
+	Point string
+	Name  string
+	Err   error
+}
+
+func (e *LifecycleError) Error() string {
+	return fmt.Sprintf("extpoints: can't %s %s extension %q: %s", e.Op, e.Point, e.Name, e.Err)
+}
+
+func (e *LifecycleError) Unwrap() error {
+	return e.Err
+}
+
+// DependencyError is returned by Start when a dependency of an extension
+// can't be satisfied.
+type DependencyError struct {
+	Point string
+	Name  string
+	// Dependency is the dependency as declared, like "StorageDriver/local".
+	Dependency string
+	Err        error
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("extpoints: %s extension %q depends on %s: %s", e.Point, e.Name, e.Dependency, e.Err)
+}
+
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
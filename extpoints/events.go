@@ -0,0 +1,212 @@
+package extpoints
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// EventKind is the kind of change an Event describes.
+type EventKind int
+
+const (
+	// Registered means an extension was registered under a new name.
+	Registered EventKind = iota
+	// Unregistered means an extension was unregistered.
+	Unregistered
+	// Replaced means an extension replaced another registered under the
+	// same name, by Replace or a DuplicatePolicy that allows it.
+	Replaced
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Registered:
+		return "registered"
+	case Unregistered:
+		return "unregistered"
+	case Replaced:
+		return "replaced"
+	}
+	return "EventKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Event describes a change to the extensions registered with a Point.
+// Events of a Registry have the extension as an interface{}.
+type Event[T any] struct {
+	Kind      EventKind
+	Point     string
+	Name      string
+	Extension T
+	// Previous is the extension that was replaced, or the zero value of T
+	// (nil) if it was registered with RegisterFactory and hadn't been
+	// created.
+	Previous T
+}
+
+// anyKind subscribes to events of every kind.
+const anyKind EventKind = -1
+
+type subscriber[T any] struct {
+	kind EventKind
+	fn   func(Event[T])
+}
+
+// events delivers the events of a Point or Registry to its subscribers.
+// Events are queued in the order the changes are made, with the point
+// locked, then delivered in that order once it's unlocked. A call that
+// finds another already delivering leaves its events to that one, so a
+// subscriber can make changes without deadlocking, and they're delivered
+// after it returns.
+type events[T any] struct {
+	mu          sync.Mutex
+	subscribers []*subscriber[T]
+	queue       []Event[T]
+	delivering  bool
+}
+
+func (e *events[T]) subscribe(kind EventKind, fn func(Event[T])) (cancel func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	sub := &subscriber[T]{kind, fn}
+	e.subscribers = append(e.subscribers, sub)
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.subscribers = slices.DeleteFunc(e.subscribers, func(s *subscriber[T]) bool {
+			return s == sub
+		})
+	}
+}
+
+// watch subscribes to every event with a channel. Sends block until the
+// event is received or the watch is canceled.
+func (e *events[T]) watch() (<-chan Event[T], func()) {
+	ch := make(chan Event[T])
+	done := make(chan struct{})
+	cancel := e.subscribe(anyKind, func(event Event[T]) {
+		select {
+		case ch <- event:
+		case <-done:
+		}
+	})
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			cancel()
+			close(done)
+		})
+	}
+}
+
+func (e *events[T]) add(event Event[T]) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queue = append(e.queue, event)
+}
+
+func (e *events[T]) deliver() {
+	e.mu.Lock()
+	if e.delivering {
+		e.mu.Unlock()
+		return
+	}
+	e.delivering = true
+	for len(e.queue) > 0 {
+		event := e.queue[0]
+		e.queue = e.queue[1:]
+		subscribers := slices.Clone(e.subscribers)
+		e.mu.Unlock()
+		e.notify(subscribers, event)
+		e.mu.Lock()
+	}
+	e.delivering = false
+	e.mu.Unlock()
+}
+
+func (e *events[T]) notify(subscribers []*subscriber[T], event Event[T]) {
+	delivered := false
+	defer func() {
+		// let later calls deliver if a subscriber panics
+		if !delivered {
+			e.mu.Lock()
+			e.delivering = false
+			e.mu.Unlock()
+		}
+	}()
+	for _, sub := range subscribers {
+		if sub.kind == anyKind || sub.kind == event.Kind {
+			sub.fn(event)
+		}
+	}
+	delivered = true
+}
+
+// OnRegister calls fn after an extension is registered under a new name,
+// returning a func that cancels the subscription. Like the other
+// subscriptions, fn is called without ep locked, in the order changes were
+// made, and may itself change ep.
+func (ep *Point[T]) OnRegister(fn func(Event[T])) (cancel func()) {
+	return ep.events.subscribe(Registered, fn)
+}
+
+// OnUnregister calls fn after an extension is unregistered, returning a
+// func that cancels the subscription.
+func (ep *Point[T]) OnUnregister(fn func(Event[T])) (cancel func()) {
+	return ep.events.subscribe(Unregistered, fn)
+}
+
+// OnReplace calls fn after an extension is replaced, returning a func that
+// cancels the subscription.
+func (ep *Point[T]) OnReplace(fn func(Event[T])) (cancel func()) {
+	return ep.events.subscribe(Replaced, fn)
+}
+
+// Watch returns a channel receiving every event of ep, and a func that
+// cancels the watch. The channel isn't buffered or closed; until the watch
+// is canceled, each event waits to be received before any later event of
+// ep is delivered.
+func (ep *Point[T]) Watch() (<-chan Event[T], func()) {
+	return ep.events.watch()
+}
+
+// OnRegister calls fn after an extension is registered with any extension
+// point in the registry. See Point.OnRegister.
+func (r *Registry) OnRegister(fn func(Event[any])) (cancel func()) {
+	return r.events.subscribe(Registered, fn)
+}
+
+// OnUnregister calls fn after an extension is unregistered from any
+// extension point in the registry. See Point.OnUnregister.
+func (r *Registry) OnUnregister(fn func(Event[any])) (cancel func()) {
+	return r.events.subscribe(Unregistered, fn)
+}
+
+// OnReplace calls fn after an extension is replaced in any extension point
+// in the registry. See Point.OnReplace.
+func (r *Registry) OnReplace(fn func(Event[any])) (cancel func()) {
+	return r.events.subscribe(Replaced, fn)
+}
+
+// Watch returns a channel receiving the events of every extension point in
+// the registry. See Point.Watch.
+func (r *Registry) Watch() (<-chan Event[any], func()) {
+	return r.events.watch()
+}
+
+// forward passes the events of ep on to the registry r.
+func (ep *Point[T]) forward(r *Registry) {
+	ep.events.subscribe(anyKind, func(event Event[T]) {
+		forwarded := Event[any]{
+			Kind:      event.Kind,
+			Point:     event.Point,
+			Name:      event.Name,
+			Extension: event.Extension,
+		}
+		if event.Kind == Replaced {
+			forwarded.Previous = event.Previous
+		}
+		r.events.add(forwarded)
+		r.events.deliver()
+	})
+}
@@ -0,0 +1,236 @@
+// Package extpoints is the runtime library for code generated by
+// go-extpoints. Generated extension point singletons are thin typed
+// wrappers around Point, registered with a Registry for their package.
+//
+// It can also be used without code generation by declaring extension
+// points with New:
+//
+//	var Subcommands = extpoints.New[Subcommand]()
+//
+//	func init() {
+//		extpoints.RegisterExtension(new(HelloCommand), "hello")
+//	}
+package extpoints
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// point is the part of an extension point that doesn't depend on its type,
+// used by a Registry to register extensions without knowing their types.
+type point interface {
+	extensionType() reflect.Type
+	// registerValue and unregister queue events, delivered by deliver
+	// once the registry is unlocked. They return the extension replaced
+	// or unregistered, to be stopped by stopRemoved.
+	registerValue(extension interface{}, name string, opts []Option) (interface{}, error)
+	unregister(name string) (interface{}, bool)
+	deliver()
+	create() error
+	members() ([]member, error)
+	registered(extension interface{}) bool
+	dependency(typ reflect.Type, name string) (reflect.Value, []member, error)
+	snapshot() (restore func())
+	Seal()
+}
+
+// Registry tracks a set of extension points, usually those generated for
+// one package, so extensions can be registered with all the points for
+// the extension types they implement.
+type Registry struct {
+	mu      sync.Mutex
+	points  map[string]point
+	order   []string
+	aliases map[string]string
+	events  events[any]
+
+	// lifecycle serializes Start and Stop, and started is the extensions
+	// started in order, nil if the registry isn't started.
+	lifecycle sync.Mutex
+	started   []member
+}
+
+// DefaultRegistry is the registry of extension points declared with New,
+// and of generated extension points when generated with -default-registry.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		points:  make(map[string]point),
+		aliases: make(map[string]string),
+	}
+}
+
+func (r *Registry) add(name string, aliases []string, ep point) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, n := range append([]string{name}, aliases...) {
+		if _, exists := r.lookup(n); exists {
+			panic("extpoints: extension point " + n + " already exists in registry")
+		}
+	}
+	r.points[name] = ep
+	r.order = append(r.order, name)
+	for _, alias := range aliases {
+		r.aliases[alias] = name
+	}
+}
+
+// lookup returns the extension point with the given name or alias. The
+// registry must be locked.
+func (r *Registry) lookup(name string) (point, bool) {
+	if ep, ok := r.points[name]; ok {
+		return ep, true
+	}
+	ep, ok := r.points[r.aliases[name]]
+	return ep, ok
+}
+
+func (r *Registry) extensionTypes(extension interface{}) []string {
+	var ifaces []string
+	typ := reflect.TypeOf(extension)
+	for _, name := range r.order {
+		if implements(typ, r.points[name].extensionType()) {
+			ifaces = append(ifaces, name)
+		}
+	}
+	return ifaces
+}
+
+// RegisterExtension registers extension with every extension point in the
+// registry for a type it implements, returning the names of those points in
+// the order they were added to the registry. The options apply to each
+// registration.
+func (r *Registry) RegisterExtension(extension interface{}, name string, opts ...Option) []string {
+	ifaces, err := r.TryRegisterExtension(extension, name, opts...)
+	panicDuplicate(err)
+	return ifaces
+}
+
+// TryRegisterExtension is like RegisterExtension, but also returns an error
+// if the extension couldn't be registered with one of the points for a type
+// it implements, or implements none of them. The error joins a
+// *RegisterError for each point that failed.
+func (r *Registry) TryRegisterExtension(extension interface{}, name string, opts ...Option) ([]string, error) {
+	var c changes
+	defer r.apply(&c)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matched := r.extensionTypes(extension)
+	if len(matched) == 0 {
+		return nil, &RegisterError{Name: name, Source: callerSource(), Err: ErrNotImplemented}
+	}
+	var ifaces []string
+	var errs []error
+	for _, iface := range matched {
+		ep := r.points[iface]
+		c.points = append(c.points, ep)
+		replaced, err := ep.registerValue(extension, name, opts)
+		if replaced != nil {
+			c.removed = append(c.removed, replaced)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return ifaces, errors.Join(errs...)
+}
+
+// MustRegisterExtension is like RegisterExtension, but panics if the
+// extension couldn't be registered with every point for a type it
+// implements.
+func (r *Registry) MustRegisterExtension(extension interface{}, name string, opts ...Option) []string {
+	ifaces, err := r.TryRegisterExtension(extension, name, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return ifaces
+}
+
+// UnregisterExtension unregisters the named extension from every extension
+// point in the registry, returning the names of the points it was removed
+// from in the order they were added to the registry.
+func (r *Registry) UnregisterExtension(name string) []string {
+	var c changes
+	defer r.apply(&c)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ifaces []string
+	for _, iface := range r.order {
+		if extension, ok := r.points[iface].unregister(name); ok {
+			c.points = append(c.points, r.points[iface])
+			c.removed = append(c.removed, extension)
+			ifaces = append(ifaces, iface)
+		}
+	}
+	return ifaces
+}
+
+// changes are the points changed and the extensions replaced or
+// unregistered with the registry locked.
+type changes struct {
+	points  []point
+	removed []interface{}
+}
+
+// apply delivers the events of changed points and stops removed
+// extensions, deferred so it runs once the registry is unlocked.
+func (r *Registry) apply(c *changes) {
+	for _, ep := range c.points {
+		ep.deliver()
+	}
+	for _, extension := range c.removed {
+		r.stopRemoved(extension)
+	}
+}
+
+// Seal seals every extension point in the registry. See Point.Seal.
+func (r *Registry) Seal() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range r.order {
+		r.points[name].Seal()
+	}
+}
+
+// RegisterExtension registers extension with the extension points in the
+// DefaultRegistry. See Registry.RegisterExtension.
+func RegisterExtension(extension interface{}, name string, opts ...Option) []string {
+	return DefaultRegistry.RegisterExtension(extension, name, opts...)
+}
+
+// TryRegisterExtension registers extension with the extension points in
+// the DefaultRegistry. See Registry.TryRegisterExtension.
+func TryRegisterExtension(extension interface{}, name string, opts ...Option) ([]string, error) {
+	return DefaultRegistry.TryRegisterExtension(extension, name, opts...)
+}
+
+// MustRegisterExtension registers extension with the extension points in
+// the DefaultRegistry. See Registry.MustRegisterExtension.
+func MustRegisterExtension(extension interface{}, name string, opts ...Option) []string {
+	return DefaultRegistry.MustRegisterExtension(extension, name, opts...)
+}
+
+// UnregisterExtension unregisters the named extension from the extension
+// points in the DefaultRegistry. See Registry.UnregisterExtension.
+func UnregisterExtension(name string) []string {
+	return DefaultRegistry.UnregisterExtension(name)
+}
+
+// implements reports whether a value of type typ can be used as an
+// extension of type iface. Func values only need to be assignable, so a
+// plain func can be registered for a named func type.
+func implements(typ, iface reflect.Type) bool {
+	if typ == nil {
+		return false
+	}
+	if iface.Kind() == reflect.Func {
+		return typ.AssignableTo(iface)
+	}
+	return typ.Implements(iface)
+}
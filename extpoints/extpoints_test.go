@@ -0,0 +1,460 @@
+package extpoints
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type greeter interface {
+	Greet() string
+}
+
+type transform func(string) string
+
+type english struct{}
+
+func (g *english) Greet() string {
+	return "hello"
+}
+
+func upper(s string) string {
+	return strings.ToUpper(s)
+}
+
+func TestRegisterAndLookup(t *testing.T) {
+	greeters := NewPoint[greeter](NewRegistry(), "greeter")
+	if !greeters.Register(new(english), "") {
+		t.Fatal("Register failed for new extension")
+	}
+	if greeters.Register(new(english), "english") {
+		t.Fatal("Register succeeded for existing name")
+	}
+	if ext := greeters.Lookup("english"); ext == nil || ext.Greet() != "hello" {
+		t.Fatal("Lookup didn't return registered extension")
+	}
+	if greeters.Lookup("french") != nil {
+		t.Fatal("Lookup returned non-existent extension")
+	}
+	if !greeters.Unregister("english") || greeters.Unregister("english") {
+		t.Fatal("Unregister didn't remove extension exactly once")
+	}
+}
+
+func TestOrder(t *testing.T) {
+	transforms := NewPoint[transform](NewRegistry(), "transform")
+	for _, name := range []string{"c", "a", "b"} {
+		name := name
+		transforms.Register(func(s string) string { return name }, name)
+	}
+	transforms.Unregister("a")
+	transforms.Register(upper, "a")
+
+	var order []string
+	for _, ext := range transforms.Ordered() {
+		order = append(order, ext("x"))
+	}
+	if strings.Join(order, ",") != "c,b,X" {
+		t.Fatalf("expected extensions in registration order, got %v", order)
+	}
+	if names := transforms.Names(); strings.Join(names, ",") != "a,b,c" {
+		t.Fatalf("expected sorted names, got %v", names)
+	}
+}
+
+func TestSorted(t *testing.T) {
+	transforms := NewPoint[transform](NewRegistry(), "transform")
+	register := func(name string, opts ...Option) {
+		transforms.Register(func(s string) string { return s + name }, name, opts...)
+	}
+	register("log")
+	register("auth", Priority(10))
+	register("gzip", After("auth"), Before("log", "missing"))
+	register("cors", Priority(100), After("auth"))
+	register("trace", Priority(5))
+
+	sorted, err := transforms.Sorted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order string
+	for _, ext := range sorted {
+		order = ext(order + " ")
+	}
+	if order != " auth cors trace gzip log" {
+		t.Fatalf("unexpected sort order:%s", order)
+	}
+
+	register("cycle", Before("auth"), After("log"))
+	_, err = transforms.Sorted()
+	cycle, ok := err.(*CycleError)
+	if !ok {
+		t.Fatalf("expected CycleError, got %v", err)
+	}
+	if strings.Join(cycle.Names, ",") != "log,auth,gzip,cors,cycle" {
+		t.Fatalf("unexpected names in cycle: %v", cycle.Names)
+	}
+}
+
+func TestRegisterExtension(t *testing.T) {
+	registry := NewRegistry()
+	greeters := NewPoint[greeter](registry, "greeter")
+	transforms := NewPoint[transform](registry, "transform")
+
+	if ifaces := registry.RegisterExtension(new(english), "english"); len(ifaces) != 1 || ifaces[0] != "greeter" {
+		t.Fatalf("expected extension registered with greeter, got %v", ifaces)
+	}
+	if ifaces := registry.RegisterExtension(upper, ""); len(ifaces) != 1 || ifaces[0] != "transform" {
+		t.Fatalf("expected func registered with transform, got %v", ifaces)
+	}
+	if ext := transforms.Lookup("upper"); ext == nil || ext("x") != "X" {
+		t.Fatal("Lookup didn't return registered func")
+	}
+	if greeters.Lookup("english") == nil {
+		t.Fatal("Lookup didn't return registered extension")
+	}
+
+	registry.RegisterExtension(upper, "english")
+	ifaces := registry.UnregisterExtension("english")
+	if len(ifaces) != 2 || ifaces[0] != "greeter" || ifaces[1] != "transform" {
+		t.Fatalf("expected extension unregistered from both points, got %v", ifaces)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	registry := NewRegistry()
+	greeters := NewPoint[greeter](registry, "greeter")
+	sealed := NewPoint[greeter](registry, "sealed")
+	sealed.Register(new(english), "")
+	sealed.Seal()
+
+	if err := greeters.TryRegister(new(english), ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, unimplemented := registry.TryRegisterExtension(upper, "upper")
+	for _, test := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"duplicate", greeters.TryRegister(new(english), "english"), ErrDuplicate},
+		{"nil", greeters.TryRegister(nil, "nil"), ErrNotImplemented},
+		{"anonymous", greeters.TryRegister(new(struct{ english }), ""), ErrInvalidName},
+		{"unimplemented", unimplemented, ErrNotImplemented},
+		{"sealed", sealed.TryRegister(new(english), "other"), ErrSealed},
+	} {
+		var regErr *RegisterError
+		if !errors.Is(test.err, test.want) || !errors.As(test.err, &regErr) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, test.err)
+		}
+	}
+
+	ifaces, err := registry.TryRegisterExtension(new(english), "other")
+	if len(ifaces) != 1 || ifaces[0] != "greeter" || !errors.Is(err, ErrSealed) {
+		t.Errorf("expected registration with greeter and ErrSealed, got %v, %v", ifaces, err)
+	}
+	if sealed.Unregister("english") {
+		t.Error("Unregister succeeded on sealed point")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("MustRegister didn't panic on duplicate")
+		} else if msg := fmt.Sprint(r); !strings.Contains(msg, `greeter extension "english" registered by`) {
+			t.Errorf("unexpected panic message: %s", msg)
+		}
+	}()
+	greeters.MustRegister(new(english), "english")
+}
+
+type spanish struct{}
+
+func (g *spanish) Greet() string {
+	return "hola"
+}
+
+func TestDuplicatePolicy(t *testing.T) {
+	greeters := NewPoint[greeter](NewRegistry(), "greeter")
+	var events []Event[greeter]
+	greeters.OnReplace(func(e Event[greeter]) {
+		events = append(events, e)
+	})
+	greeters.Register(new(english), "hello")
+	greeters.Register(new(english), "other")
+
+	if greeters.Register(new(spanish), "hello") {
+		t.Fatal("Register replaced extension with DuplicateReject")
+	}
+	greeters.SetDuplicatePolicy(DuplicateReplace)
+	if !greeters.Register(new(spanish), "hello") || greeters.Lookup("hello").Greet() != "hola" {
+		t.Fatal("Register didn't replace extension with DuplicateReplace")
+	}
+	if names := greeters.Names(); len(names) != 2 || greeters.Ordered()[0].Greet() != "hola" {
+		t.Fatal("replacement didn't keep the place of the previous extension")
+	}
+	if len(events) != 1 || events[0].Kind != Replaced || events[0].Name != "hello" ||
+		events[0].Previous.Greet() != "hello" || events[0].Extension.Greet() != "hola" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	greeters.SetDuplicatePolicy(DuplicateReject)
+	prev, err := greeters.Replace(new(english), "hello")
+	if err != nil || prev == nil || prev.Greet() != "hola" || greeters.Lookup("hello").Greet() != "hello" {
+		t.Fatalf("Replace didn't replace extension: %v, %v", prev, err)
+	}
+	if prev, err := greeters.Replace(new(english), "new"); err != nil || prev != nil {
+		t.Fatalf("Replace of unregistered name returned %v, %v", prev, err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected an event for each replacement, got %d", len(events))
+	}
+
+	greeters.SetDuplicatePolicy(DuplicateError)
+	if err := greeters.TryRegister(new(spanish), "hello"); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("expected TryRegister to return ErrDuplicate, got %v", err)
+	}
+	if err := greeters.RegisterFactory("hello", func() (greeter, error) { return new(spanish), nil }); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("expected RegisterFactory to return ErrDuplicate, got %v", err)
+	}
+	defer func() {
+		if err, ok := recover().(error); !ok || !errors.Is(err, ErrDuplicate) {
+			t.Errorf("expected panic with ErrDuplicate, got %v", err)
+		}
+	}()
+	greeters.Register(new(spanish), "hello")
+}
+
+func TestDuplicateErrorPolicy(t *testing.T) {
+	registry := NewRegistry()
+	greeters := NewPoint[greeter](registry, "greeter")
+	others := NewPoint[greeter](registry, "other")
+	others.SetDuplicatePolicy(DuplicateError)
+	others.Register(new(english), "hello")
+
+	var registered []string
+	registry.OnRegister(func(e Event[any]) {
+		registered = append(registered, e.Point)
+	})
+	ifaces, err := registry.TryRegisterExtension(new(spanish), "hello")
+	if !errors.Is(err, ErrDuplicate) || len(ifaces) != 1 || ifaces[0] != "greeter" {
+		t.Fatalf("expected error for the other point only, got %v, %v", ifaces, err)
+	}
+	if len(registered) != 1 || greeters.Lookup("hello") == nil {
+		t.Fatalf("expected extension registered with greeter, got events %v", registered)
+	}
+
+	greeters.Unregister("hello")
+	defer func() {
+		if err, ok := recover().(error); !ok || !errors.Is(err, ErrDuplicate) {
+			t.Errorf("expected panic with ErrDuplicate, got %v", err)
+		}
+		if greeters.Lookup("hello") == nil {
+			t.Error("expected extension registered with other points before panicking")
+		}
+	}()
+	registry.RegisterExtension(new(spanish), "hello")
+}
+
+func TestMeta(t *testing.T) {
+	greeters := NewPoint[greeter](NewRegistry(), "greeter")
+	greeters.Register(new(english), "english",
+		Description("Says hello"), Version("1.0"), Provider("example.com/greet"))
+	greeters.Register(new(spanish), "spanish",
+		Tags("experimental", "i18n"), Attr("lang", "es"))
+
+	meta, ok := greeters.Describe("english")
+	if !ok || meta.Name != "english" || meta.Description != "Says hello" ||
+		meta.Version != "1.0" || meta.Provider != "example.com/greet" {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+	meta, _ = greeters.Describe("spanish")
+	if !meta.HasTag("i18n") || meta.Attrs["lang"] != "es" {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+	meta.Attrs["lang"] = "en"
+	if meta, _ := greeters.Describe("spanish"); meta.Attrs["lang"] != "es" {
+		t.Fatal("Describe returned meta sharing the registered attributes")
+	}
+	if _, ok := greeters.Describe("french"); ok {
+		t.Fatal("Describe succeeded for unregistered extension")
+	}
+
+	if tagged := greeters.Tagged("experimental"); len(tagged) != 1 || tagged[0].Greet() != "hola" {
+		t.Fatalf("unexpected tagged extensions: %v", tagged)
+	}
+	filtered := greeters.Filter(func(m Meta) bool {
+		return m.Version != ""
+	})
+	if len(filtered) != 1 || filtered[0].Greet() != "hello" {
+		t.Fatalf("unexpected filtered extensions: %v", filtered)
+	}
+}
+
+type named struct{}
+
+func (n named) Greet() string {
+	return "hi"
+}
+
+func (n named) ExtensionName() string {
+	return "canonical"
+}
+
+type codec[T any] struct{}
+
+func mapFunc[T any](v T) T {
+	return v
+}
+
+func TestExtensionName(t *testing.T) {
+	closure := func(s string) string { return s }
+	for _, test := range []struct {
+		extension interface{}
+		want      string
+	}{
+		{new(english), "english"},
+		{english{}, "english"},
+		{&spanish{}, "spanish"},
+		{named{}, "canonical"},
+		{codec[int]{}, "codec"},
+		{new(codec[string]), "codec"},
+		{upper, "upper"},
+		{transform(upper), "upper"},
+		{named{}.Greet, "Greet"},
+		{new(english).Greet, "Greet"},
+		{mapFunc[string], "mapFunc"},
+		{closure, ""},
+		{transform(closure), "transform"},
+		{struct{}{}, ""},
+		{nil, ""},
+	} {
+		if got := extensionName(test.extension); got != test.want {
+			t.Errorf("extensionName(%T) = %q, want %q", test.extension, got, test.want)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	registry := NewRegistry()
+	greeters := NewPoint[greeter](registry, "greeter")
+
+	var log []string
+	record := func(e Event[greeter]) {
+		log = append(log, e.Kind.String()+" "+e.Name)
+	}
+	greeters.OnRegister(func(e Event[greeter]) {
+		record(e)
+		// changes made by subscribers are delivered after they return
+		if e.Name == "english" {
+			greeters.Register(new(spanish), "")
+		}
+	})
+	greeters.OnUnregister(record)
+	cancel := greeters.OnReplace(record)
+
+	var registryLog []string
+	registry.OnRegister(func(e Event[any]) {
+		registryLog = append(registryLog, e.Point+" "+e.Name)
+	})
+
+	greeters.Register(new(english), "")
+	greeters.Replace(new(english), "spanish")
+	cancel()
+	greeters.Replace(new(spanish), "spanish")
+	registry.UnregisterExtension("english")
+
+	want := "registered english, registered spanish, replaced spanish, unregistered english"
+	if got := strings.Join(log, ", "); got != want {
+		t.Errorf("expected events %q, got %q", want, got)
+	}
+	if got := strings.Join(registryLog, ", "); got != "greeter english, greeter spanish" {
+		t.Errorf("unexpected registry events: %q", got)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	registry := NewRegistry()
+	greeters := NewPoint[greeter](registry, "greeter")
+	events, cancel := registry.Watch()
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		greeters.Register(new(english), "")
+		greeters.Unregister("english")
+	}()
+	for _, want := range []EventKind{Registered, Unregistered} {
+		if e := <-events; e.Kind != want || e.Name != "english" {
+			t.Errorf("expected %s english, got %s %s", want, e.Kind, e.Name)
+		}
+	}
+	<-done
+
+	// a canceled watch doesn't hold up delivery
+	cancel()
+	greeters.Register(new(english), "")
+}
+
+type farewell interface {
+	Farewell() string
+}
+
+type goodbye struct{}
+
+func (g *goodbye) Farewell() string {
+	return "goodbye"
+}
+
+func TestNew(t *testing.T) {
+	farewells := New[farewell]()
+	if farewells.Name() != "farewell" {
+		t.Fatalf("expected point named after its type, got %s", farewells.Name())
+	}
+	if ifaces := RegisterExtension(new(goodbye), ""); len(ifaces) != 1 || ifaces[0] != "farewell" {
+		t.Fatalf("expected extension registered with farewell, got %v", ifaces)
+	}
+	if ext := farewells.Lookup("goodbye"); ext == nil || ext.Farewell() != "goodbye" {
+		t.Fatal("Lookup didn't return registered extension")
+	}
+	if ifaces := UnregisterExtension("goodbye"); len(ifaces) != 1 {
+		t.Fatalf("expected extension unregistered, got %v", ifaces)
+	}
+}
+
+type fake struct{}
+
+func (g *fake) Greet() string {
+	return "fake"
+}
+
+func TestSnapshot(t *testing.T) {
+	registry := NewRegistry()
+	greeters := NewPoint[greeter](registry, "greeter")
+	greeters.Register(new(english), "")
+
+	snapshot := registry.Snapshot()
+	greeters.Replace(new(fake), "english")
+	greeters.Register(new(fake), "")
+	greeters.SetDuplicatePolicy(DuplicateReplace)
+	greeters.Seal()
+	NewPoint[transform](registry, "transform")
+	snapshot.Restore()
+
+	if ext := greeters.Lookup("english"); ext == nil || ext.Greet() != "hello" {
+		t.Fatal("Restore didn't restore replaced extension")
+	}
+	if names := greeters.Names(); strings.Join(names, ",") != "english" {
+		t.Fatalf("expected only english after Restore, got %v", names)
+	}
+	if !greeters.Register(new(fake), "") {
+		t.Fatal("Restore didn't unseal point")
+	}
+	if err := greeters.TryRegister(new(fake), "english"); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("expected Restore to restore DuplicateReject, got %v", err)
+	}
+	if ifaces := registry.RegisterExtension(upper, ""); len(ifaces) != 0 {
+		t.Fatalf("expected point added after snapshot to be removed, got %v", ifaces)
+	}
+}
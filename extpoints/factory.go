@@ -0,0 +1,138 @@
+package extpoints
+
+import "fmt"
+
+// errNilExtension is returned for an extension whose factory returns nil.
+var errNilExtension = fmt.Errorf("factory returned nil: %w", ErrNotImplemented)
+
+// RegisterFactory registers an extension under name that's created by
+// factory when it's first looked up, for extensions that are expensive to
+// create and might not be used. The extension is created once and kept,
+// unless the PerCall option is given. If factory fails, or returns nil,
+// which fails with ErrNotImplemented, the extension is left out of the
+// results of All, Ordered and the like, and factory is called again on the
+// next lookup; use LookupE to get the error.
+//
+// The Extension of events for an extension registered with a factory is
+// the zero value of T (nil) if it hasn't been created.
+func (ep *Point[T]) RegisterFactory(name string, factory func() (T, error), opts ...Option) error {
+	defer ep.events.deliver()
+	reg := &registration[T]{options: newOptions(opts), factory: factory}
+	replaced, err := ep.insert(reg, name, false)
+	ep.stopReplaced(replaced)
+	return err
+}
+
+// PerCall makes an extension registered with RegisterFactory be created
+// every time it's looked up, instead of once. Extensions created per call
+// aren't driven by Start and Stop, or injected as dependencies.
+func PerCall() Option {
+	return func(o *options) {
+		o.perCall = true
+	}
+}
+
+// LookupE returns the named extension like Lookup, but returns the error
+// of its factory if it was registered with RegisterFactory and fails. It
+// returns the zero value of T (nil) and no error if the extension isn't
+// registered.
+func (ep *Point[T]) LookupE(name string) (T, error) {
+	ep.mu.Lock()
+	reg, ok := ep.extensions[name]
+	ep.mu.Unlock()
+	if !ok {
+		var zero T
+		return zero, nil
+	}
+	extension, err := reg.get()
+	if err != nil {
+		return extension, &LifecycleError{Op: "create", Point: ep.name, Name: name, Err: err}
+	}
+	return extension, nil
+}
+
+// get returns the extension of reg, creating it if it's made by a factory.
+// The point mustn't be locked, so the factory can use it.
+func (reg *registration[T]) get() (T, error) {
+	if reg.factory == nil {
+		return reg.extension, nil
+	}
+	if reg.perCall {
+		return reg.call()
+	}
+	reg.creating.Lock()
+	defer reg.creating.Unlock()
+	if extension, ok := reg.current(); ok {
+		return extension, nil
+	}
+	extension, err := reg.call()
+	if err != nil {
+		return extension, err
+	}
+	reg.mu.Lock()
+	reg.extension, reg.created = extension, true
+	reg.mu.Unlock()
+	return extension, nil
+}
+
+// call calls the factory of reg, failing if it returns nil without an
+// error, as a nil extension can't be used.
+func (reg *registration[T]) call() (T, error) {
+	extension, err := reg.factory()
+	if err == nil && isNil(extension) {
+		var zero T
+		return zero, errNilExtension
+	}
+	return extension, err
+}
+
+// current returns the extension of reg without creating it, and false if
+// it's made by a factory and hasn't been created.
+func (reg *registration[T]) current() (T, bool) {
+	if reg.factory == nil {
+		return reg.extension, true
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.extension, reg.created
+}
+
+// registrations returns the registrations of the named extensions. The
+// point must be locked.
+func (ep *Point[T]) registrations(names []string) []*registration[T] {
+	regs := make([]*registration[T], 0, len(names))
+	for _, name := range names {
+		regs = append(regs, ep.extensions[name])
+	}
+	return regs
+}
+
+// extensions returns the extensions of regs, leaving out those whose
+// factories fail. The point mustn't be locked.
+func extensions[T any](regs []*registration[T]) []T {
+	extensions := make([]T, 0, len(regs))
+	for _, reg := range regs {
+		if extension, err := reg.get(); err == nil {
+			extensions = append(extensions, extension)
+		}
+	}
+	return extensions
+}
+
+// create creates the extensions registered with factories to be created
+// once, so Start can drive them.
+func (ep *Point[T]) create() error {
+	ep.mu.Lock()
+	names := append([]string(nil), ep.order...)
+	regs := ep.registrations(names)
+	ep.mu.Unlock()
+	for i, reg := range regs {
+		if reg.factory == nil || reg.perCall {
+			continue
+		}
+		if _, err := reg.get(); err != nil {
+			return &LifecycleError{Op: "create", Point: ep.name, Name: names[i], Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package extpoints
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegisterFactory(t *testing.T) {
+	greeters := NewPoint[greeter](NewRegistry(), "greeter")
+
+	created := 0
+	err := greeters.RegisterFactory("english", func() (greeter, error) {
+		created++
+		return new(english), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 0 {
+		t.Fatal("factory called before lookup")
+	}
+	if ext := greeters.Lookup("english"); ext == nil || ext.Greet() != "hello" {
+		t.Fatal("Lookup didn't return created extension")
+	}
+	greeters.Lookup("english")
+	greeters.All()
+	if created != 1 {
+		t.Fatalf("expected singleton created once, got %d", created)
+	}
+
+	perCall := 0
+	greeters.RegisterFactory("spanish", func() (greeter, error) {
+		perCall++
+		return new(spanish), nil
+	}, PerCall())
+	greeters.Lookup("spanish")
+	greeters.Lookup("spanish")
+	if perCall != 2 {
+		t.Fatalf("expected per call extension created on each lookup, got %d", perCall)
+	}
+
+	failure := errors.New("no connection")
+	greeters.RegisterFactory("broken", func() (greeter, error) {
+		return nil, failure
+	})
+	if _, err := greeters.LookupE("broken"); !errors.Is(err, failure) {
+		t.Fatalf("expected factory error from LookupE, got %v", err)
+	}
+	if ext, err := greeters.LookupE("missing"); ext != nil || err != nil {
+		t.Fatalf("expected nothing for unregistered extension, got %v, %v", ext, err)
+	}
+	greeters.RegisterFactory("nil", func() (greeter, error) {
+		return nil, nil
+	})
+	if ext, err := greeters.LookupE("nil"); ext != nil || !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("expected ErrNotImplemented for nil extension, got %v, %v", ext, err)
+	}
+	if ordered := greeters.Ordered(); len(ordered) != 2 {
+		t.Fatalf("expected failed extensions left out, got %d extensions", len(ordered))
+	}
+	var replaced []Event[greeter]
+	cancel := greeters.OnReplace(func(e Event[greeter]) { replaced = append(replaced, e) })
+	defer cancel()
+	if previous, err := greeters.Replace(new(english), "broken"); previous != nil || err != nil {
+		t.Fatalf("expected nil for uncreated extension replaced, got %v, %v", previous, err)
+	}
+	if len(replaced) != 1 || replaced[0].Kind != Replaced || replaced[0].Previous != nil {
+		t.Fatalf("expected Replaced event without previous extension, got %v", replaced)
+	}
+	if err := greeters.RegisterFactory("", func() (greeter, error) { return nil, nil }); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("expected ErrInvalidName for unnamed factory, got %v", err)
+	}
+}
+
+func TestStartCreatesFactories(t *testing.T) {
+	registry := NewRegistry()
+	greeters := NewPoint[greeter](registry, "greeter")
+
+	var log []string
+	greeters.RegisterFactory("lazy", func() (greeter, error) {
+		log = append(log, "create lazy")
+		return &service{name: "lazy", log: &log}, nil
+	})
+	if err := registry.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	registry.Stop(context.Background())
+	if len(log) != 4 || log[0] != "create lazy" || log[3] != "stop lazy" {
+		t.Fatalf("unexpected lifecycle of lazy extension: %v", log)
+	}
+
+	greeters.RegisterFactory("broken", func() (greeter, error) {
+		return nil, errors.New("failed")
+	})
+	var lifecycleErr *LifecycleError
+	if err := registry.Start(context.Background()); !errors.As(err, &lifecycleErr) || lifecycleErr.Op != "create" {
+		t.Fatalf("expected error creating extension, got %v", err)
+	}
+}
+
+func TestFactoryUsingPoint(t *testing.T) {
+	greeters := NewPoint[greeter](NewRegistry(), "greeter")
+	greeters.Register(new(english), "")
+
+	// the factory uses the point while another goroutine unregisters it,
+	// which locks the point and checks whether it was created
+	creating, unregistered := make(chan struct{}), make(chan struct{})
+	greeters.RegisterFactory("lazy", func() (greeter, error) {
+		close(creating)
+		<-unregistered
+		return greeters.Lookup("english"), nil
+	})
+	go func() {
+		<-creating
+		greeters.Unregister("lazy")
+		close(unregistered)
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		greeters.Lookup("lazy")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("factory using its point deadlocked with Unregister")
+	}
+}
@@ -0,0 +1,259 @@
+package extpoints
+
+import (
+	"context"
+	"errors"
+	"log"
+	"reflect"
+	"slices"
+)
+
+// Initializer is implemented by extensions that need to be initialized
+// before any extension is started.
+type Initializer interface {
+	Init(ctx context.Context) error
+}
+
+// Starter is implemented by extensions that need to be started.
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// Stopper is implemented by extensions that need to be stopped.
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// member is an extension registered with an extension point.
+type member struct {
+	point     string
+	name      string
+	extension interface{}
+}
+
+// Start initializes, then starts, the extensions of every extension point
+// in the registry, in the order the points were added and then in the
+// order returned by Sorted, except that extensions come after the
+// extensions they depend on. An extension registered with several points
+// is only initialized and started once. Extensions registered with
+// RegisterFactory are created first, unless they're created per call.
+//
+// Before that, the dependencies of extensions are injected into their
+// struct fields tagged with extpoint, which name an extension point, or
+// one of its aliases like the variable of a generated point, optionally
+// followed by "/" and the name of an extension:
+//
+//	type Server struct {
+//		Drivers []StorageDriver          `extpoint:"StorageDrivers"`
+//		ByName  map[string]StorageDriver `extpoint:"StorageDrivers"`
+//		Local   StorageDriver            `extpoint:"StorageDrivers/local"`
+//		Cache   StorageDriver            `extpoint:"StorageDrivers/cache,optional"`
+//	}
+//
+// A whole point is injected as a slice of its extensions in Sorted order or
+// a map of them by name, or as the *Point itself, and a named extension as
+// itself. Extensions can also declare dependencies that aren't injected
+// with a Requires method. Start returns a *DependencyError if a dependency
+// isn't registered and isn't optional, and a *CycleError if dependencies
+// form a cycle. If an extension fails to start, the
+// extensions already started are stopped in reverse order, and Start
+// returns a *LifecycleError along with any errors stopping them.
+//
+// Factories and Requires methods are called with the registry unlocked, so
+// they can use it, but extensions they register or unregister aren't
+// accounted for. Likewise extensions registered after Start aren't
+// started, but started extensions that are unregistered or replaced are
+// stopped.
+func (r *Registry) Start(ctx context.Context) error {
+	r.lifecycle.Lock()
+	defer r.lifecycle.Unlock()
+	r.mu.Lock()
+	if r.started != nil {
+		r.mu.Unlock()
+		return errors.New("extpoints: registry already started")
+	}
+	points := make([]point, 0, len(r.order))
+	for _, name := range r.order {
+		points = append(points, r.points[name])
+	}
+	r.mu.Unlock()
+	// factories are called with the registry unlocked, so they can use it
+	for _, ep := range points {
+		if err := ep.create(); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	members, err := r.members()
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	// so are Requires methods, before the dependencies are resolved
+	requires := requirements(members)
+	r.mu.Lock()
+	members, err = r.resolve(members, requires)
+	if err != nil {
+		r.mu.Unlock()
+		return err
+	}
+	r.started = []member{}
+	r.mu.Unlock()
+
+	for _, m := range members {
+		if initializer, ok := m.extension.(Initializer); ok {
+			if err := initializer.Init(ctx); err != nil {
+				r.stop(ctx)
+				return &LifecycleError{Op: "initialize", Point: m.point, Name: m.name, Err: err}
+			}
+		}
+	}
+	for _, m := range members {
+		if starter, ok := m.extension.(Starter); ok {
+			if err := starter.Start(ctx); err != nil {
+				err = &LifecycleError{Op: "start", Point: m.point, Name: m.name, Err: err}
+				return errors.Join(err, r.stop(ctx))
+			}
+		}
+		r.mu.Lock()
+		r.started = append(r.started, m)
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// Stop stops the started extensions of the registry in the reverse of the
+// order they were started, returning the errors of any that fail joined
+// together as *LifecycleErrors. The registry can then be started again.
+func (r *Registry) Stop(ctx context.Context) error {
+	r.lifecycle.Lock()
+	defer r.lifecycle.Unlock()
+	return r.stop(ctx)
+}
+
+func (r *Registry) stop(ctx context.Context) error {
+	r.mu.Lock()
+	started := r.started
+	r.started = nil
+	r.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := stopMember(ctx, started[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopRemoved stops a started extension that has been unregistered or
+// replaced, unless it's still registered with another extension point.
+func (r *Registry) stopRemoved(extension interface{}) {
+	r.mu.Lock()
+	i := slices.IndexFunc(r.started, func(m member) bool {
+		return sameExtension(m.extension, extension)
+	})
+	if i < 0 || r.registered(extension) {
+		r.mu.Unlock()
+		return
+	}
+	m := r.started[i]
+	r.started = slices.Delete(r.started, i, i+1)
+	r.mu.Unlock()
+
+	if err := stopMember(context.Background(), m); err != nil {
+		log.Print(err)
+	}
+}
+
+// members returns the extensions of every point for Start, each only
+// once. The registry must be locked.
+func (r *Registry) members() ([]member, error) {
+	var members []member
+	for _, name := range r.order {
+		points, err := r.points[name].members()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range points {
+			if !slices.ContainsFunc(members, func(other member) bool {
+				return sameExtension(other.extension, m.extension)
+			}) {
+				members = append(members, m)
+			}
+		}
+	}
+	return members, nil
+}
+
+// registered reports whether extension is registered with any point. The
+// registry must be locked.
+func (r *Registry) registered(extension interface{}) bool {
+	for _, name := range r.order {
+		if r.points[name].registered(extension) {
+			return true
+		}
+	}
+	return false
+}
+
+func stopMember(ctx context.Context, m member) error {
+	stopper, ok := m.extension.(Stopper)
+	if !ok {
+		return nil
+	}
+	if err := stopper.Stop(ctx); err != nil {
+		return &LifecycleError{Op: "stop", Point: m.point, Name: m.name, Err: err}
+	}
+	return nil
+}
+
+// sameExtension reports whether a and b are the same extension. Values of
+// types that can't be compared, like funcs, are never the same.
+func sameExtension(a, b interface{}) bool {
+	typ := reflect.TypeOf(a)
+	if typ == nil || typ != reflect.TypeOf(b) || !typ.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// members returns the registered extensions in the order returned by
+// Sorted, leaving out those made by factories that haven't been created.
+func (ep *Point[T]) members() ([]member, error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	names, err := ep.sortedNames()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]member, 0, len(names))
+	for _, name := range names {
+		if extension, ok := ep.extensions[name].current(); ok {
+			members = append(members, member{ep.name, name, extension})
+		}
+	}
+	return members, nil
+}
+
+func (ep *Point[T]) registered(extension interface{}) bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	for _, reg := range ep.extensions {
+		if current, ok := reg.current(); ok && sameExtension(current, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start starts the extensions of the DefaultRegistry. See Registry.Start.
+func Start(ctx context.Context) error {
+	return DefaultRegistry.Start(ctx)
+}
+
+// Stop stops the extensions of the DefaultRegistry. See Registry.Stop.
+func Stop(ctx context.Context) error {
+	return DefaultRegistry.Stop(ctx)
+}
@@ -0,0 +1,83 @@
+package extpoints
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type service struct {
+	name string
+	fail bool
+	log  *[]string
+}
+
+func (s *service) Greet() string {
+	return s.name
+}
+
+func (s *service) Init(ctx context.Context) error {
+	*s.log = append(*s.log, "init "+s.name)
+	return nil
+}
+
+func (s *service) Start(ctx context.Context) error {
+	if s.fail {
+		return errors.New("failed")
+	}
+	*s.log = append(*s.log, "start "+s.name)
+	return nil
+}
+
+func (s *service) Stop(ctx context.Context) error {
+	*s.log = append(*s.log, "stop "+s.name)
+	return nil
+}
+
+func TestLifecycle(t *testing.T) {
+	registry := NewRegistry()
+	greeters := NewPoint[greeter](registry, "greeter")
+	NewPoint[Starter](registry, "starter")
+
+	var log []string
+	expect := func(want string) {
+		t.Helper()
+		if got := strings.Join(log, ", "); got != want {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+		log = nil
+	}
+	// registered with both points, but only started once
+	registry.RegisterExtension(&service{name: "b", log: &log}, "b")
+	greeters.Register(&service{name: "a", log: &log}, "a", Priority(10))
+	greeters.Register(&service{name: "c", fail: true, log: &log}, "c")
+
+	err := registry.Start(context.Background())
+	var lifecycleErr *LifecycleError
+	if !errors.As(err, &lifecycleErr) || lifecycleErr.Op != "start" || lifecycleErr.Name != "c" {
+		t.Fatalf("expected error starting c, got %v", err)
+	}
+	expect("init a, init b, init c, start a, start b, stop b, stop a")
+
+	greeters.Unregister("c")
+	if err := registry.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expect("init a, init b, start a, start b")
+	if err := registry.Start(context.Background()); err == nil {
+		t.Fatal("Start succeeded on started registry")
+	}
+
+	greeters.Unregister("a")
+	expect("stop a")
+	// still registered with the starter point
+	greeters.Unregister("b")
+	expect("")
+	greeters.Register(&service{name: "d", log: &log}, "d")
+
+	if err := registry.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expect("stop b")
+}
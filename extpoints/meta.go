@@ -0,0 +1,128 @@
+package extpoints
+
+import (
+	"maps"
+	"slices"
+)
+
+// Meta describes a registered extension, for listing extensions along
+// with where they come from.
+type Meta struct {
+	Name        string
+	Description string
+	Version     string
+	// Provider is who provides the extension, like a module path.
+	Provider string
+	Tags     []string
+	Attrs    map[string]string
+	// Source is where the extension was registered from.
+	Source Source
+}
+
+// HasTag reports whether the extension is tagged with tag.
+func (m Meta) HasTag(tag string) bool {
+	return slices.Contains(m.Tags, tag)
+}
+
+// describe returns a copy of the Meta of a registration, which can be
+// handed out without ep locked.
+func (reg *registration[T]) describe(name string) Meta {
+	meta := reg.meta
+	meta.Name = name
+	meta.Tags = slices.Clone(meta.Tags)
+	meta.Attrs = maps.Clone(meta.Attrs)
+	return meta
+}
+
+// Description sets the description of an extension in its Meta.
+func Description(description string) Option {
+	return func(o *options) {
+		o.meta.Description = description
+	}
+}
+
+// Version sets the version of an extension in its Meta.
+func Version(version string) Option {
+	return func(o *options) {
+		o.meta.Version = version
+	}
+}
+
+// Provider sets who provides an extension in its Meta.
+func Provider(provider string) Option {
+	return func(o *options) {
+		o.meta.Provider = provider
+	}
+}
+
+// Tags adds tags to an extension's Meta, like "experimental".
+func Tags(tags ...string) Option {
+	return func(o *options) {
+		o.meta.Tags = append(o.meta.Tags, tags...)
+	}
+}
+
+// Attr sets an arbitrary attribute of an extension in its Meta.
+func Attr(key, value string) Option {
+	return func(o *options) {
+		if o.meta.Attrs == nil {
+			o.meta.Attrs = make(map[string]string)
+		}
+		o.meta.Attrs[key] = value
+	}
+}
+
+// Describe returns the Meta of the named extension, and false if it isn't
+// registered.
+func (ep *Point[T]) Describe(name string) (Meta, bool) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	reg, ok := ep.extensions[name]
+	if !ok {
+		return Meta{}, false
+	}
+	return reg.describe(name), true
+}
+
+// DescribeAll returns the Meta of every registered extension, in the order
+// they were registered.
+func (ep *Point[T]) DescribeAll() []Meta {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	metas := make([]Meta, 0, len(ep.order))
+	for _, name := range ep.order {
+		metas = append(metas, ep.extensions[name].describe(name))
+	}
+	return metas
+}
+
+// Filter returns the registered extensions whose Meta match, in the order
+// they were registered. The point isn't locked while match is called, and
+// factories are only called for extensions that match.
+func (ep *Point[T]) Filter(match func(Meta) bool) []T {
+	ep.mu.Lock()
+	regs := make([]*registration[T], 0, len(ep.order))
+	metas := make([]Meta, 0, len(ep.order))
+	for _, name := range ep.order {
+		reg := ep.extensions[name]
+		regs = append(regs, reg)
+		metas = append(metas, reg.describe(name))
+	}
+	ep.mu.Unlock()
+
+	var matched []*registration[T]
+	for i, meta := range metas {
+		if match(meta) {
+			matched = append(matched, regs[i])
+		}
+	}
+	return extensions(matched)
+}
+
+// Tagged returns the registered extensions tagged with tag, in the order
+// they were registered.
+func (ep *Point[T]) Tagged(tag string) []T {
+	return ep.Filter(func(m Meta) bool {
+		return m.HasTag(tag)
+	})
+}
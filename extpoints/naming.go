@@ -0,0 +1,76 @@
+package extpoints
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Namer is implemented by extensions that declare their own name, used when
+// they're registered without one.
+type Namer interface {
+	ExtensionName() string
+}
+
+// extensionName returns the name an extension is registered under if it's
+// registered without one, or "" if it has no usable name:
+//
+//   - the name returned by ExtensionName, if it implements Namer
+//   - the name of a func or method, like Upper for pkg.Upper or s.Upper
+//   - the name of the type of a closure, if it has a named func type
+//   - the name of the type of a value or pointer, like Server for a
+//     Server or *Server
+func extensionName(extension interface{}) string {
+	if namer, ok := extension.(Namer); ok {
+		return namer.ExtensionName()
+	}
+	typ := reflect.TypeOf(extension)
+	if typ == nil {
+		return ""
+	}
+	if typ.Kind() == reflect.Func {
+		if name := funcName(reflect.ValueOf(extension)); name != "" {
+			return name
+		}
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return trimTypeArgs(typ.Name())
+}
+
+// funcName returns the name of a func or method value, or "" for a closure.
+func funcName(fn reflect.Value) string {
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil {
+		return ""
+	}
+	// like pkg.Func, pkg.Map[...] for a generic func, pkg.(*T).Method-fm
+	// for a method value, or pkg.Func.func1, pkg.init.0.func1 and
+	// pkg.glob..func1 for closures
+	name := strings.TrimSuffix(f.Name(), "-fm")
+	name = strings.ReplaceAll(name[strings.LastIndex(name, "/")+1:], "[...]", "")
+	parts := strings.Split(name, ".")
+	for _, part := range parts[1:] {
+		if part == "" || isClosurePart(part) {
+			return ""
+		}
+	}
+	return parts[len(parts)-1]
+}
+
+// isClosurePart reports whether part of a func name is one the compiler
+// gives closures, like func1, or the 0 of init.0.
+func isClosurePart(part string) bool {
+	digits := strings.TrimPrefix(part, "func")
+	return digits != "" && strings.Trim(digits, "0123456789") == ""
+}
+
+// trimTypeArgs removes the type arguments from the name of a generic type,
+// like [int] in Codec[int].
+func trimTypeArgs(name string) string {
+	if i := strings.Index(name, "["); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
@@ -0,0 +1,46 @@
+package extpoints
+
+// Option configures how an extension is registered, setting its place in
+// Sorted or its Meta.
+type Option func(*options)
+
+type options struct {
+	priority int
+	before   []string
+	after    []string
+	meta     Meta
+	perCall  bool
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Priority sets the priority of an extension for Sorted. Extensions with a
+// higher priority come first, unless Before or After say otherwise. The
+// default priority is 0.
+func Priority(priority int) Option {
+	return func(o *options) {
+		o.priority = priority
+	}
+}
+
+// Before makes Sorted return the extension before the named extensions.
+// Names that aren't registered are ignored.
+func Before(names ...string) Option {
+	return func(o *options) {
+		o.before = append(o.before, names...)
+	}
+}
+
+// After makes Sorted return the extension after the named extensions.
+// Names that aren't registered are ignored.
+func After(names ...string) Option {
+	return func(o *options) {
+		o.after = append(o.after, names...)
+	}
+}
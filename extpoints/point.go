@@ -0,0 +1,377 @@
+package extpoints
+
+import (
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Point is an extension point for extensions of type T, which is an
+// interface or func type.
+type Point[T any] struct {
+	mu         sync.Mutex
+	name       string
+	iface      reflect.Type
+	extensions map[string]*registration[T]
+	order      []string
+	sealed     bool
+	duplicates DuplicatePolicy
+	events     events[T]
+	registry   *Registry
+}
+
+// registration is a registered extension along with its options.
+type registration[T any] struct {
+	extension T
+	options
+
+	// factory creates the extension if it was registered with
+	// RegisterFactory. creating is held while it runs, so it runs once at
+	// a time, and mu guards extension and created only briefly, so current
+	// never waits on a factory that may be using the point.
+	factory  func() (T, error)
+	creating sync.Mutex
+	mu       sync.Mutex
+	created  bool
+}
+
+// New returns an extension point for T added to the DefaultRegistry, named
+// after T. It panics if the DefaultRegistry already has a point by that name.
+func New[T any]() *Point[T] {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	name := typ.Name()
+	if name == "" {
+		name = typ.String()
+	}
+	return NewPoint[T](DefaultRegistry, name)
+}
+
+// NewPoint returns an extension point with the given name, added to the
+// registry r. Dependencies of extensions can refer to the point by its
+// name or any of its aliases. It panics if r already has a point by one of
+// those names.
+func NewPoint[T any](r *Registry, name string, aliases ...string) *Point[T] {
+	ep := &Point[T]{
+		name:       name,
+		iface:      reflect.TypeOf((*T)(nil)).Elem(),
+		extensions: make(map[string]*registration[T]),
+		registry:   r,
+	}
+	r.add(name, aliases, ep)
+	ep.forward(r)
+	return ep
+}
+
+// Name returns the name of the extension point.
+func (ep *Point[T]) Name() string {
+	return ep.name
+}
+
+func (ep *Point[T]) extensionType() reflect.Type {
+	return ep.iface
+}
+
+// Register registers an extension under name. If name is "", the name of
+// the extension's type or func is used. It returns false if the extension
+// can't be registered; use TryRegister to find out why. If the name is
+// taken, what happens depends on the point's DuplicatePolicy. Options set the
+// extension's place in the order returned by Sorted.
+func (ep *Point[T]) Register(extension T, name string, opts ...Option) bool {
+	err := ep.TryRegister(extension, name, opts...)
+	panicDuplicate(err)
+	return err == nil
+}
+
+// TryRegister is like Register, but returns a *RegisterError wrapping
+// ErrDuplicate, ErrNotImplemented, ErrSealed or ErrInvalidName if the
+// extension can't be registered.
+func (ep *Point[T]) TryRegister(extension T, name string, opts ...Option) error {
+	defer ep.events.deliver()
+	replaced, err := ep.register(extension, name, opts, false)
+	ep.stopReplaced(replaced)
+	return err
+}
+
+// Replace registers an extension under name, replacing any extension
+// already registered under it whatever the DuplicatePolicy, and returns
+// the previous extension or the zero value of T (nil) if there wasn't one.
+// It also returns nil if the previous extension was registered with
+// RegisterFactory and hadn't been created; it isn't created just to be
+// returned. A replacement keeps the place of the previous extension in
+// Ordered.
+func (ep *Point[T]) Replace(extension T, name string, opts ...Option) (T, error) {
+	defer ep.events.deliver()
+	replaced, err := ep.register(extension, name, opts, true)
+	ep.stopReplaced(replaced)
+	return replaced, err
+}
+
+// stopReplaced stops a replaced extension if it was started.
+func (ep *Point[T]) stopReplaced(replaced T) {
+	if !isNil(replaced) {
+		ep.registry.stopRemoved(replaced)
+	}
+}
+
+// register registers an extension, returning the extension it replaced.
+// It queues an event for the change, which the caller delivers once ep is
+// unlocked.
+func (ep *Point[T]) register(extension T, name string, opts []Option, replace bool) (T, error) {
+	return ep.insert(&registration[T]{extension: extension, options: newOptions(opts)}, name, replace)
+}
+
+// insert registers reg under name, for register and RegisterFactory.
+func (ep *Point[T]) insert(reg *registration[T], name string, replace bool) (T, error) {
+	var zero T
+	source := callerSource()
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.sealed {
+		return zero, &RegisterError{Point: ep.name, Name: name, Source: source, Err: ErrSealed}
+	}
+	if reg.factory == nil && isNil(reg.extension) {
+		return zero, &RegisterError{Point: ep.name, Name: name, Source: source, Err: ErrNotImplemented}
+	}
+	if name == "" && reg.factory == nil {
+		name = extensionName(reg.extension)
+	}
+	if name == "" {
+		return zero, &RegisterError{Point: ep.name, Name: name, Source: source, Err: ErrInvalidName}
+	}
+	reg.meta.Source = source
+	prev, exists := ep.extensions[name]
+	if !exists {
+		ep.extensions[name] = reg
+		ep.order = append(ep.order, name)
+		ep.events.add(Event[T]{Kind: Registered, Point: ep.name, Name: name, Extension: reg.extension})
+		return zero, nil
+	}
+
+	policy := ep.duplicates
+	if replace {
+		policy = DuplicateReplace
+	}
+	switch policy {
+	case DuplicateReplaceWarn:
+		log.Printf("extpoints: %s extension %q registered by %s, replaced from %s",
+			ep.name, name, prev.meta.Source, source)
+		fallthrough
+	case DuplicateReplace:
+		previous, _ := prev.current()
+		ep.extensions[name] = reg
+		ep.events.add(Event[T]{
+			Kind:      Replaced,
+			Point:     ep.name,
+			Name:      name,
+			Extension: reg.extension,
+			Previous:  previous,
+		})
+		return previous, nil
+	}
+	return zero, &RegisterError{
+		Point:    ep.name,
+		Name:     name,
+		Source:   source,
+		Existing: prev.meta.Source,
+		Err:      ErrDuplicate,
+		fatal:    policy == DuplicateError,
+	}
+}
+
+// SetDuplicatePolicy sets what happens when an extension is registered
+// under a name that's already taken. The default is DuplicateReject.
+func (ep *Point[T]) SetDuplicatePolicy(policy DuplicatePolicy) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.duplicates = policy
+}
+
+// MustRegister is like Register, but panics if the extension can't be
+// registered. It's meant for registering extensions in init functions,
+// where a mistake should stop the program from starting.
+func (ep *Point[T]) MustRegister(extension T, name string, opts ...Option) {
+	if err := ep.TryRegister(extension, name, opts...); err != nil {
+		panic(err)
+	}
+}
+
+func (ep *Point[T]) registerValue(extension interface{}, name string, opts []Option) (interface{}, error) {
+	if ep.iface.Kind() == reflect.Func {
+		extension = reflect.ValueOf(extension).Convert(ep.iface).Interface()
+	}
+	replaced, err := ep.register(extension.(T), name, opts, false)
+	if isNil(replaced) {
+		return nil, err
+	}
+	return replaced, err
+}
+
+func (ep *Point[T]) deliver() {
+	ep.events.deliver()
+}
+
+// Seal stops any more extensions from being registered or unregistered,
+// so the set of extensions can't change once a program has started.
+func (ep *Point[T]) Seal() {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.sealed = true
+}
+
+// Unregister unregisters the named extension, returning false if it wasn't
+// registered or the extension point is sealed. A started extension is
+// stopped, unless it's still registered with another extension point.
+func (ep *Point[T]) Unregister(name string) bool {
+	defer ep.events.deliver()
+	extension, ok := ep.unregister(name)
+	if ok {
+		ep.registry.stopRemoved(extension)
+	}
+	return ok
+}
+
+// unregister unregisters the named extension, returning it and queueing an
+// event for the caller to deliver.
+func (ep *Point[T]) unregister(name string) (interface{}, bool) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	reg, exists := ep.extensions[name]
+	if !exists || ep.sealed {
+		return nil, false
+	}
+	delete(ep.extensions, name)
+	extension, _ := reg.current()
+	ep.events.add(Event[T]{Kind: Unregistered, Point: ep.name, Name: name, Extension: extension})
+	for i, n := range ep.order {
+		if n == name {
+			ep.order = append(ep.order[:i], ep.order[i+1:]...)
+			break
+		}
+	}
+	return extension, true
+}
+
+// Lookup returns the named extension, or the zero value of T (nil) if it
+// isn't registered or its factory fails.
+func (ep *Point[T]) Lookup(name string) T {
+	extension, _ := ep.LookupE(name)
+	return extension
+}
+
+// Select looks up each of the named extensions in order, nil or not.
+func (ep *Point[T]) Select(names []string) []T {
+	var selected []T
+	for _, name := range names {
+		selected = append(selected, ep.Lookup(name))
+	}
+	return selected
+}
+
+// All returns all registered extensions keyed by name. Like the other
+// methods returning several extensions, it leaves out those whose
+// factories fail.
+func (ep *Point[T]) All() map[string]T {
+	ep.mu.Lock()
+	names := append([]string(nil), ep.order...)
+	regs := ep.registrations(names)
+	ep.mu.Unlock()
+	all := make(map[string]T)
+	for i, reg := range regs {
+		if extension, err := reg.get(); err == nil {
+			all[names[i]] = extension
+		}
+	}
+	return all
+}
+
+// Ordered returns all registered extensions in the order they were
+// registered.
+func (ep *Point[T]) Ordered() []T {
+	ep.mu.Lock()
+	regs := ep.registrations(ep.order)
+	ep.mu.Unlock()
+	return extensions(regs)
+}
+
+// Sorted returns all registered extensions ordered by their Before and
+// After constraints, then by descending Priority, then in the order they
+// were registered. It returns a *CycleError if the constraints conflict.
+func (ep *Point[T]) Sorted() ([]T, error) {
+	ep.mu.Lock()
+	names, err := ep.sortedNames()
+	regs := ep.registrations(names)
+	ep.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return extensions(regs), nil
+}
+
+// sortedNames returns the names of the extensions in the order returned by
+// Sorted. ep must be locked.
+func (ep *Point[T]) sortedNames() ([]string, error) {
+	// edges[a] are the extensions that must come after a
+	edges := make(map[string][]string)
+	incoming := make(map[string]int)
+	for _, name := range ep.order {
+		reg := ep.extensions[name]
+		for _, before := range reg.before {
+			if _, ok := ep.extensions[before]; ok {
+				edges[name] = append(edges[name], before)
+				incoming[before]++
+			}
+		}
+		for _, after := range reg.after {
+			if _, ok := ep.extensions[after]; ok {
+				edges[after] = append(edges[after], name)
+				incoming[name]++
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(ep.order))
+	done := make(map[string]bool)
+	for len(sorted) < len(ep.order) {
+		next := ""
+		for _, name := range ep.order {
+			if done[name] || incoming[name] > 0 {
+				continue
+			}
+			if next == "" || ep.extensions[name].priority > ep.extensions[next].priority {
+				next = name
+			}
+		}
+		if next == "" {
+			var cycle []string
+			for _, name := range ep.order {
+				if !done[name] {
+					cycle = append(cycle, name)
+				}
+			}
+			return nil, &CycleError{Point: ep.name, Names: cycle}
+		}
+		done[next] = true
+		sorted = append(sorted, next)
+		for _, name := range edges[next] {
+			incoming[name]--
+		}
+	}
+	return sorted, nil
+}
+
+// Names returns the sorted names of all registered extensions.
+func (ep *Point[T]) Names() []string {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	names := append([]string(nil), ep.order...)
+	sort.Strings(names)
+	return names
+}
+
+// isNil reports whether extension is a nil interface or func, which can't
+// be used as an extension.
+func isNil(extension interface{}) bool {
+	v := reflect.ValueOf(extension)
+	return !v.IsValid() || v.Kind() == reflect.Func && v.IsNil()
+}
@@ -0,0 +1,68 @@
+package extpoints
+
+import (
+	"maps"
+	"slices"
+)
+
+// Snapshot is the state of a registry's extension points and their
+// extensions at some moment, which the registry can be restored to.
+type Snapshot struct {
+	registry *Registry
+	points   map[string]point
+	order    []string
+	aliases  map[string]string
+	restores []func()
+}
+
+// Snapshot returns the current state of the registry, so tests can
+// register fakes and then restore it:
+//
+//	defer registry.Snapshot().Restore()
+func (r *Registry) Snapshot() *Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := &Snapshot{
+		registry: r,
+		points:   maps.Clone(r.points),
+		order:    slices.Clone(r.order),
+		aliases:  maps.Clone(r.aliases),
+	}
+	for _, name := range r.order {
+		s.restores = append(s.restores, r.points[name].snapshot())
+	}
+	return s
+}
+
+// Restore restores the registry to the snapshot, removing extension points
+// added since and setting the extensions of each point back as they were,
+// along with whether it was sealed and its DuplicatePolicy. It doesn't
+// deliver events, or start or stop extensions.
+func (s *Snapshot) Restore() {
+	r := s.registry
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.points = maps.Clone(s.points)
+	r.order = slices.Clone(s.order)
+	r.aliases = maps.Clone(s.aliases)
+	for _, restore := range s.restores {
+		restore()
+	}
+}
+
+// snapshot returns a func restoring the extensions and settings of ep as
+// they are now.
+func (ep *Point[T]) snapshot() (restore func()) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	extensions := maps.Clone(ep.extensions)
+	order := slices.Clone(ep.order)
+	sealed, duplicates := ep.sealed, ep.duplicates
+	return func() {
+		ep.mu.Lock()
+		defer ep.mu.Unlock()
+		ep.extensions = maps.Clone(extensions)
+		ep.order = slices.Clone(order)
+		ep.sealed, ep.duplicates = sealed, duplicates
+	}
+}
@@ -0,0 +1,64 @@
+package extpoints
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Source is where an extension was registered from.
+type Source struct {
+	// Package is the import path of the registering package.
+	Package string
+	File    string
+	Line    int
+}
+
+func (s Source) String() string {
+	if s.Package == "" {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s (%s:%d)", s.Package, filepath.Base(s.File), s.Line)
+}
+
+var (
+	// packagePath is the import path of this package, whose frames are
+	// skipped to find the caller registering an extension.
+	packagePath = reflect.TypeOf(Source{}).PkgPath()
+
+	// registerFuncs are the generated top-level registration functions,
+	// which are skipped too.
+	registerFuncs = []string{"RegisterExtension", "TryRegisterExtension", "MustRegisterExtension"}
+)
+
+// callerSource returns the Source of the first caller outside this
+// package and the generated registration functions.
+func callerSource() Source {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		pkg, fn := splitFuncName(frame.Function)
+		if pkg != packagePath && !slices.Contains(registerFuncs, fn) {
+			return Source{Package: pkg, File: frame.File, Line: frame.Line}
+		}
+		if !more {
+			return Source{}
+		}
+	}
+}
+
+// splitFuncName splits a qualified func name like
+// "github.com/a/x.(*T).Method" into its package path and the rest.
+func splitFuncName(name string) (pkg, fn string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+2+dot:]
+}
@@ -0,0 +1,51 @@
+package extpoints_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/progrium/go-extpoints/extpoints"
+)
+
+type noop interface {
+	Noop()
+}
+
+type noopImpl struct{}
+
+func (n *noopImpl) Noop() {}
+
+var (
+	registry = extpoints.NewRegistry()
+	noops    = extpoints.NewPoint[noop](registry, "noop")
+)
+
+// RegisterExtension stands in for a generated registration function.
+func RegisterExtension(extension interface{}, name string) []string {
+	return registry.RegisterExtension(extension, name)
+}
+
+func TestSource(t *testing.T) {
+	const pkg = "github.com/progrium/go-extpoints/extpoints_test"
+
+	noops.Register(new(noopImpl), "direct")
+	RegisterExtension(new(noopImpl), "generated")
+	for _, name := range []string{"direct", "generated"} {
+		meta, _ := noops.Describe(name)
+		if meta.Source.Package != pkg || filepath.Base(meta.Source.File) != "source_test.go" || meta.Source.Line == 0 {
+			t.Errorf("unexpected source for %s: %+v", name, meta.Source)
+		}
+	}
+
+	err := noops.TryRegister(new(noopImpl), "direct")
+	var regErr *extpoints.RegisterError
+	if !errors.As(err, &regErr) || regErr.Existing.Package != pkg || regErr.Source.Package != pkg {
+		t.Fatalf("expected duplicate error with sources, got %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, `noop extension "direct" registered by `+pkg+" (source_test.go:") ||
+		!strings.Contains(msg, "rejected from "+pkg+" (source_test.go:") {
+		t.Errorf("unexpected error message: %s", msg)
+	}
+}
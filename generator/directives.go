@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+const directivePrefix = "//extpoints:"
+
+// Directives are comments of the form //extpoints:<name> [args...] in the
+// doc comment of an extension type.
+const (
+	directiveIgnore = "ignore" // never generate an extension point
+	directivePoint  = "point"  // generate an extension point, required with -explicit
+
+	// generate an extension point for an instance of a generic type
+	directiveInstantiate = "instantiate"
+
+	// override the names of the extension point variable and wrapper type
+	directiveVar  = "var"
+	directiveType = "type"
+)
+
+var knownDirectives = map[string]bool{
+	directiveIgnore: true,
+	directivePoint:  true,
+
+	directiveInstantiate: true,
+	directiveVar:         true,
+	directiveType:        true,
+}
+
+type directive struct {
+	Name string
+	Args []string
+	Pos  token.Pos
+}
+
+type directives []directive
+
+func (d directives) has(name string) bool {
+	for _, dir := range d {
+		if dir.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// typeDirectives collects the directives that apply to a type spec. Those
+// in the spec's own doc comment take precedence over those documenting the
+// enclosing grouped declaration.
+func typeDirectives(fset *token.FileSet, genDecl *ast.GenDecl, typeSpec *ast.TypeSpec) (directives, error) {
+	declDirs, err := parseDirectives(fset, genDecl.Doc)
+	if err != nil {
+		return nil, err
+	}
+	specDirs, err := parseDirectives(fset, typeSpec.Doc)
+	if err != nil {
+		return nil, err
+	}
+	if specDirs.has(directiveIgnore) || specDirs.has(directivePoint) {
+		declDirs = declDirs.without(directiveIgnore, directivePoint)
+	}
+	return append(declDirs, specDirs...), nil
+}
+
+// name returns the identifier given by the last directive with the given
+// name, or an empty string if there isn't one.
+func (d directives) name(fset *token.FileSet, name string) (string, error) {
+	var ident string
+	for _, dir := range d {
+		if dir.Name != name {
+			continue
+		}
+		if len(dir.Args) != 1 || !token.IsIdentifier(dir.Args[0]) {
+			return "", fmt.Errorf("%s: expected %s%s <identifier>", fset.Position(dir.Pos), directivePrefix, name)
+		}
+		ident = dir.Args[0]
+	}
+	return ident, nil
+}
+
+func (d directives) without(names ...string) directives {
+	var dirs directives
+	for _, dir := range d {
+		excluded := false
+		for _, name := range names {
+			if dir.Name == name {
+				excluded = true
+			}
+		}
+		if !excluded {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// parseDirectives collects the directives in a doc comment.
+func parseDirectives(fset *token.FileSet, doc *ast.CommentGroup) (directives, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	var dirs directives
+	for _, comment := range doc.List {
+		if !strings.HasPrefix(comment.Text, directivePrefix) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(comment.Text, directivePrefix))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%s: missing directive name", fset.Position(comment.Pos()))
+		}
+		if !knownDirectives[fields[0]] {
+			return nil, fmt.Errorf("%s: unknown directive %q", fset.Position(comment.Pos()), fields[0])
+		}
+		dirs = append(dirs, directive{fields[0], fields[1:], comment.Pos()})
+	}
+	if dirs.has(directiveIgnore) && dirs.has(directivePoint) {
+		return nil, fmt.Errorf("%s: conflicting %s%s and %s%s directives",
+			fset.Position(doc.Pos()), directivePrefix, directiveIgnore, directivePrefix, directivePoint)
+	}
+	return dirs, nil
+}
@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ErrNoTypes is returned when a package has no extension types left to
+// generate after applying directives and type selection.
+var ErrNoTypes = errors.New("no extension types found")
+
+// NoPackageError is returned when the package directory can't be read or
+// doesn't contain any Go files.
+type NoPackageError struct {
+	Dir string
+	Err error
+}
+
+func (e *NoPackageError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("no package found in %s: %s", e.Dir, e.Err)
+	}
+	return fmt.Sprintf("no package found in %s", e.Dir)
+}
+
+func (e *NoPackageError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is returned when the package can't be parsed or type-checked.
+type ParseError struct {
+	Dir    string
+	Errors []packages.Error
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("could not load package in %s:\n\t%s", e.Dir, strings.Join(msgs, "\n\t"))
+}
+
+// MixedPackagesError is returned when the files of the package directory
+// declare different package names.
+type MixedPackagesError struct {
+	Dir string
+	// Files maps each package name to the files declaring it.
+	Files map[string][]string
+}
+
+func (e *MixedPackagesError) Error() string {
+	var names []string
+	for name := range e.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var found []string
+	for _, name := range names {
+		found = append(found, fmt.Sprintf("%s (%s)", name, strings.Join(e.Files[name], ", ")))
+	}
+	return fmt.Sprintf("found mixed packages in %s: %s", e.Dir, strings.Join(found, " and "))
+}
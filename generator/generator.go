@@ -0,0 +1,232 @@
+// Package generator generates extension point singletons for the extension
+// types declared in a Go package. It's what the go-extpoints command runs,
+// and can be used directly by other build tooling.
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// RuntimePackage is the import path of the package backing generated
+// extension points.
+const RuntimePackage = "github.com/progrium/go-extpoints/extpoints"
+
+// Config describes what to generate.
+type Config struct {
+	// Dir is the directory of the package declaring the extension types.
+	// It defaults to the current directory.
+	Dir string
+	// Output is the file to write. It defaults to extpoints.go in Dir.
+	Output string
+	// Package overrides the package name of the output file.
+	Package string
+	// Types, if not empty, selects which extension types to generate.
+	Types []string
+	// Exclude lists extension types not to generate.
+	Exclude []string
+	// Tags are build tags applied when loading the package.
+	Tags []string
+	// Tests also loads the package's _test.go files, which must declare the
+	// same package, for generating into a _test.go output file.
+	Tests bool
+	// Explicit only generates types marked with //extpoints:point.
+	Explicit bool
+	// SelfContained generates code that includes its own registry instead
+	// of importing RuntimePackage.
+	SelfContained bool
+	// DefaultRegistry adds the generated extension points to the runtime
+	// package's DefaultRegistry, shared with points declared by hand.
+	DefaultRegistry bool
+	// Naming is the strategy for naming extension point variables not
+	// named by an //extpoints:var directive. It defaults to NamingPlural.
+	Naming Naming
+	// DryRun renders the output without writing it.
+	DryRun bool
+}
+
+// Result describes what was generated.
+type Result struct {
+	Package         string
+	Output          string
+	ExtensionPoints []ExtensionPoint
+	Skipped         []SkippedType
+	// Source is the formatted generated code.
+	Source []byte
+	// Written is true if Output was written, which it isn't for a dry run
+	// or if it already had the generated contents.
+	Written bool
+}
+
+// SkippedType is a type declared in the package that wasn't generated.
+type SkippedType struct {
+	Name   string
+	Reason string
+}
+
+// ExtensionPoint is a generated extension point singleton.
+type ExtensionPoint struct {
+	Name string
+	// Expr is the Go expression for the extension type. It's the same as
+	// Name unless the extension type is an instance of a generic type.
+	Expr string
+	// Var is the name of the extension point variable.
+	Var string
+	// Type is the name of the extension point's wrapper type.
+	Type string
+}
+
+// Generate loads the package in cfg.Dir and generates extension points for
+// its extension types.
+func Generate(cfg Config) (Result, error) {
+	if cfg.Dir == "" {
+		cfg.Dir = "."
+	}
+	if cfg.Output == "" {
+		cfg.Output = filepath.Join(cfg.Dir, "extpoints.go")
+	}
+	result := Result{Output: cfg.Output}
+	if cfg.SelfContained && cfg.DefaultRegistry {
+		return result, fmt.Errorf("self-contained extension points can't use the default registry")
+	}
+
+	pkg, err := loadPackage(cfg)
+	if err != nil {
+		return result, err
+	}
+	result.Package = pkg.Name
+	if cfg.Package != "" {
+		result.Package = cfg.Package
+	}
+
+	idents := runtimeIdents
+	tmpl := runtimeTemplate
+	if cfg.SelfContained {
+		idents = selfContainedIdents
+		tmpl = selfContainedTemplate
+	}
+	extpoints, imports, skipped, err := processPackage(pkg, cfg.Explicit, idents)
+	if err != nil {
+		return result, err
+	}
+	result.ExtensionPoints, result.Skipped, err = filterTypes(extpoints, cfg.Types, cfg.Exclude)
+	result.Skipped = append(skipped, result.Skipped...)
+	if err != nil {
+		return result, err
+	}
+	if len(result.ExtensionPoints) == 0 {
+		return result, fmt.Errorf("%s: %w", cfg.Dir, ErrNoTypes)
+	}
+	if err := applyNaming(result.ExtensionPoints, cfg.Naming); err != nil {
+		return result, err
+	}
+	if err := checkCollisions(pkg.Types, idents, imports, result.ExtensionPoints); err != nil {
+		return result, err
+	}
+
+	result.Source, err = renderExtpoints(tmpl, templateData{
+		Package:         result.Package,
+		Imports:         withoutTemplateImports(idents, imports),
+		ExtensionPoints: result.ExtensionPoints,
+		DefaultRegistry: cfg.DefaultRegistry,
+	})
+	if err != nil {
+		return result, err
+	}
+	if !cfg.DryRun {
+		result.Written, err = writeFile(cfg.Output, result.Source)
+	}
+	return result, err
+}
+
+// filterTypes applies the Types and Exclude selections to the found
+// extension points, failing if a selected type wasn't found at all.
+func filterTypes(extpoints []ExtensionPoint, selected, excluded []string) ([]ExtensionPoint, []SkippedType, error) {
+	found := make(map[string]bool)
+	for _, ep := range extpoints {
+		found[ep.Name] = true
+	}
+	allowed := make(map[string]bool)
+	for _, iface := range selected {
+		if !found[iface] {
+			return nil, nil, fmt.Errorf("could not find extension type %s", iface)
+		}
+		allowed[iface] = true
+	}
+	denied := make(map[string]bool)
+	for _, iface := range excluded {
+		denied[iface] = true
+	}
+
+	var filtered []ExtensionPoint
+	var skipped []SkippedType
+	for _, ep := range extpoints {
+		switch {
+		case denied[ep.Name]:
+			skipped = append(skipped, SkippedType{ep.Name, "excluded"})
+		case len(allowed) > 0 && !allowed[ep.Name]:
+			skipped = append(skipped, SkippedType{ep.Name, "not selected"})
+		default:
+			filtered = append(filtered, ep)
+		}
+	}
+	return filtered, skipped, nil
+}
+
+type templateData struct {
+	Package         string
+	Imports         []Import
+	ExtensionPoints []ExtensionPoint
+	DefaultRegistry bool
+}
+
+func renderExtpoints(tmpl string, data templateData) ([]byte, error) {
+	var buf bytes.Buffer
+	outputTemplate := template.Must(template.New("render").Parse(tmpl))
+	if err := outputTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return formatSource(buf.Bytes())
+}
+
+// writeFile replaces the file at path by renaming a temporary file over it,
+// so it's never left half written. The file isn't touched at all if it
+// already has the given contents.
+func writeFile(path string, data []byte) (changed bool, err error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		existing, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(existing, data) {
+			return false, nil
+		}
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err = tmp.Close(); err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,196 @@
+package generator
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func extensionPointNames(result Result) []string {
+	var names []string
+	for _, ep := range result.ExtensionPoints {
+		names = append(names, ep.Name)
+	}
+	return names
+}
+
+func TestGenerateFindsAllTypes(t *testing.T) {
+	result, err := Generate(Config{Dir: "testdata/hooks", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Closer", "Handler", "Starter", "Stopper", "Hook"}
+	if names := extensionPointNames(result); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected extension points %v, got %v", expected, names)
+	}
+	skipped := map[string]string{
+		"helper":  "unexported",
+		"Event":   "ignored by //extpoints:ignore",
+		"Options": "neither an interface nor a func type",
+	}
+	if len(result.Skipped) != len(skipped) {
+		t.Fatalf("expected %d skipped types, got %v", len(skipped), result.Skipped)
+	}
+	for _, skip := range result.Skipped {
+		if skipped[skip.Name] != skip.Reason {
+			t.Fatalf("unexpected skip of %s: %s", skip.Name, skip.Reason)
+		}
+	}
+	if result.Package != "hooks" || len(result.Source) == 0 || result.Written {
+		t.Fatalf("unexpected dry run result: %+v", result)
+	}
+}
+
+func TestGenerateTests(t *testing.T) {
+	result, err := Generate(Config{Dir: "testdata/hooks", Tests: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Closer", "Handler", "Starter", "Stopper", "Hook", "TestHook"}
+	if names := extensionPointNames(result); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected extension points %v, got %v", expected, names)
+	}
+
+	if _, err := Generate(Config{Dir: "testdata/external", DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Generate(Config{Dir: "testdata/external", Tests: true, DryRun: true})
+	var mixed *MixedPackagesError
+	if !errors.As(err, &mixed) {
+		t.Fatalf("expected MixedPackagesError, got %v", err)
+	}
+}
+
+func TestGenerateInstantiatesGenericTypes(t *testing.T) {
+	result, err := Generate(Config{Dir: "testdata/generic", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ExtensionPoint{
+		{Name: "ByteCodec", Expr: "Codec[[]byte]", Var: "ByteCodecs", Type: "byteCodecExt"},
+		{Name: "RequestCodec", Expr: "Codec[*http.Request]", Var: "RequestCodecs", Type: "requestCodecExt"},
+		{Name: "ContextCodec", Expr: "Codec[context.Context]", Var: "ContextCodecs", Type: "contextCodecExt"},
+		{Name: "HTMLCodec", Expr: "Codec[*template.Template]", Var: "HTMLCodecs", Type: "htmlCodecExt"},
+		{Name: "TextCodec", Expr: "Codec[*template2.Template]", Var: "TextCodecs", Type: "textCodecExt"},
+		{Name: "IntCodec", Expr: "IntCodec", Var: "IntCodecs", Type: "intCodecExt"},
+		{Name: "Middleware", Expr: "Middleware", Var: "Middlewares", Type: "middlewareExt"},
+	}
+	if !reflect.DeepEqual(result.ExtensionPoints, expected) {
+		t.Fatalf("expected extension points %v, got %v", expected, result.ExtensionPoints)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Name != "Mapper" {
+		t.Fatalf("expected Mapper to be skipped, got %v", result.Skipped)
+	}
+	for _, code := range []string{
+		`"net/http"`, "*extpoints.Point[Codec[*http.Request]]", "func NewExtensionPoints() *ExtensionPoints",
+		`"html/template"`, `template2 "text/template"`,
+	} {
+		if !strings.Contains(string(result.Source), code) {
+			t.Fatalf("expected generated code to contain %q", code)
+		}
+	}
+	if n := strings.Count(string(result.Source), `"context"`); n != 1 {
+		t.Fatalf("expected context to be imported once, got %d imports", n)
+	}
+}
+
+func TestGenerateNaming(t *testing.T) {
+	result, err := Generate(Config{Dir: "testdata/naming", Exclude: []string{"Series"}, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ExtensionPoint{
+		{Name: "HTTPHandler", Expr: "HTTPHandler", Var: "HTTPHandlers", Type: "httpHandlerExt"},
+		{Name: "Status", Expr: "Status", Var: "StatusChecks", Type: "statusExt"},
+	}
+	if !reflect.DeepEqual(result.ExtensionPoints, expected) {
+		t.Fatalf("expected extension points %v, got %v", expected, result.ExtensionPoints)
+	}
+
+	// Series is already plural, so its variable collides with the type
+	_, err = Generate(Config{Dir: "testdata/naming", DryRun: true})
+	var collision *NameCollisionError
+	if !errors.As(err, &collision) {
+		t.Fatalf("expected NameCollisionError, got %v", err)
+	}
+	if len(collision.Collisions) != 1 || collision.Collisions[0].Name != "Series" {
+		t.Fatalf("expected Series to collide, got %v", collision.Collisions)
+	}
+
+	result, err = Generate(Config{Dir: "testdata/naming", Naming: NamingPoint, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep := result.ExtensionPoints[2]; ep.Var != "SeriesPoint" {
+		t.Fatalf("expected SeriesPoint variable, got %s", ep.Var)
+	}
+}
+
+func TestGenerateSelectsTypes(t *testing.T) {
+	result, err := Generate(Config{
+		Dir:     "testdata/hooks",
+		Types:   []string{"Handler", "Hook", "Starter"},
+		Exclude: []string{"Starter"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Handler", "Hook"}
+	if names := extensionPointNames(result); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected extension points %v, got %v", expected, names)
+	}
+
+	_, err = Generate(Config{Dir: "testdata/hooks", Types: []string{"Missing"}, DryRun: true})
+	if err == nil {
+		t.Fatal("expected error selecting a missing type")
+	}
+}
+
+func TestGenerateWritesOnlyChanges(t *testing.T) {
+	cfg := Config{Dir: "testdata/hooks", Output: filepath.Join(t.TempDir(), "extpoints.go")}
+	result, err := Generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Written {
+		t.Fatal("expected output to be written")
+	}
+	result, err = Generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Written {
+		t.Fatal("expected unchanged output not to be written")
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := Generate(Config{Dir: "testdata/hooks", Explicit: true, DryRun: true})
+	if !errors.Is(err, ErrNoTypes) {
+		t.Fatalf("expected ErrNoTypes, got %v", err)
+	}
+
+	_, err = Generate(Config{Dir: "testdata/missing", DryRun: true})
+	var noPackage *NoPackageError
+	if !errors.As(err, &noPackage) {
+		t.Fatalf("expected NoPackageError, got %v", err)
+	}
+
+	_, err = Generate(Config{Dir: "testdata/broken", DryRun: true})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+
+	_, err = Generate(Config{Dir: "testdata/mixed", DryRun: true})
+	var mixed *MixedPackagesError
+	if !errors.As(err, &mixed) {
+		t.Fatalf("expected MixedPackagesError, got %v", err)
+	}
+	if len(mixed.Files) != 2 {
+		t.Fatalf("expected two packages, got %v", mixed.Files)
+	}
+}
@@ -0,0 +1,351 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// packageDir is what a scan of the package directory turned up before
+// loading it.
+type packageDir struct {
+	Name string
+	// Overlay blanks out previously generated files so a stale extpoints.go
+	// can't affect loading the extension types it was made from.
+	Overlay map[string][]byte
+}
+
+// scanDir checks that the Go files in dir that are part of the build all
+// declare the same package. Test files are only considered if tests is set.
+func scanDir(dir, output string, tags []string, tests bool) (*packageDir, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, &NoPackageError{Dir: dir, Err: err}
+	}
+	outputAbs, err := filepath.Abs(output)
+	if err != nil {
+		return nil, err
+	}
+	ctx := build.Default
+	ctx.BuildTags = tags
+
+	scan := &packageDir{Overlay: make(map[string][]byte)}
+	files := make(map[string][]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		if strings.HasSuffix(name, "_test.go") && !tests {
+			continue
+		}
+		if match, err := ctx.MatchFile(dir, name); err != nil {
+			return nil, &ParseError{Dir: dir, Errors: []packages.Error{{Msg: err.Error(), Kind: packages.ParseError}}}
+		} else if !match {
+			continue
+		}
+		path, err := filepath.Abs(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return nil, &ParseError{Dir: dir, Errors: []packages.Error{{Msg: err.Error(), Kind: packages.ParseError}}}
+		}
+		if name == "extpoints.go" || path == outputAbs {
+			scan.Overlay[path] = []byte("package " + f.Name.Name + "\n")
+		}
+		files[f.Name.Name] = append(files[f.Name.Name], name)
+		scan.Name = f.Name.Name
+	}
+	if len(files) == 0 {
+		return nil, &NoPackageError{Dir: dir}
+	}
+	if len(files) > 1 {
+		return nil, &MixedPackagesError{Dir: dir, Files: files}
+	}
+	return scan, nil
+}
+
+func loadPackage(cfg Config) (*packages.Package, error) {
+	scan, err := scanDir(cfg.Dir, cfg.Output, cfg.Tags, cfg.Tests)
+	if err != nil {
+		return nil, err
+	}
+	loadCfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+			packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     cfg.Dir,
+		Tests:   cfg.Tests,
+		Overlay: scan.Overlay,
+	}
+	if len(cfg.Tags) > 0 {
+		loadCfg.BuildFlags = []string{"-tags", strings.Join(cfg.Tags, ",")}
+	}
+	pkgs, err := packages.Load(loadCfg, ".")
+	if err != nil {
+		return nil, &NoPackageError{Dir: cfg.Dir, Err: err}
+	}
+	pkg := selectPackage(pkgs, scan.Name)
+	if pkg == nil {
+		return nil, fmt.Errorf("expected package %s in %s, found %d packages", scan.Name, cfg.Dir, len(pkgs))
+	}
+	var errs []packages.Error
+	packages.Visit([]*packages.Package{pkg}, nil, func(pkg *packages.Package) {
+		errs = append(errs, pkg.Errors...)
+	})
+	if len(errs) > 0 {
+		return nil, &ParseError{Dir: cfg.Dir, Errors: errs}
+	}
+	// _ext.go files are type-checked, as the extension types may use their
+	// declarations, but aren't scanned for extension types
+	syntax := pkg.Syntax[:0:0]
+	for _, f := range pkg.Syntax {
+		if !strings.HasSuffix(pkg.Fset.Position(f.Package).Filename, "_ext.go") {
+			syntax = append(syntax, f)
+		}
+	}
+	pkg.Syntax = syntax
+	return pkg, nil
+}
+
+// selectPackage picks the loaded package with the given name, preferring
+// its test variant when tests were loaded, since it includes the test files.
+func selectPackage(pkgs []*packages.Package, name string) *packages.Package {
+	var selected *packages.Package
+	for _, pkg := range pkgs {
+		if pkg.Name != name {
+			continue
+		}
+		if strings.HasSuffix(pkg.ID, ".test]") {
+			return pkg
+		}
+		selected = pkg
+	}
+	return selected
+}
+
+func processPackage(pkg *packages.Package, explicit bool, templateIdents []string) ([]ExtensionPoint, []Import, []SkippedType, error) {
+	var extpoints []ExtensionPoint
+	var skipped []SkippedType
+	imports := &importSet{pkg: pkg.Types, names: make(map[string]string), reserved: templateIdents}
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			found, skips, err := identifyTypes(pkg, imports, decl, explicit)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			extpoints = append(extpoints, found...)
+			skipped = append(skipped, skips...)
+		}
+	}
+	return extpoints, imports.list(), skipped, nil
+}
+
+// identifyTypes returns all extension types declared by decl in declaration
+// order, along with any declared types that were passed over. In explicit
+// mode only types marked with //extpoints:point are returned.
+func identifyTypes(pkg *packages.Package, imports *importSet, decl ast.Decl, explicit bool) (extpoints []ExtensionPoint, skipped []SkippedType, err error) {
+	genDecl, ok := decl.(*ast.GenDecl)
+	if !ok || genDecl.Tok != token.TYPE {
+		return
+	}
+	for _, spec := range genDecl.Specs {
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok || typeSpec.Name == nil {
+			continue
+		}
+		dirs, err := typeDirectives(pkg.Fset, genDecl, typeSpec)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := typeSpec.Name.Name
+		if dirs.has(directiveIgnore) {
+			skipped = append(skipped, SkippedType{name, "ignored by " + directivePrefix + directiveIgnore})
+			continue
+		}
+		obj := pkg.TypesInfo.Defs[typeSpec.Name]
+		if obj == nil {
+			skipped = append(skipped, SkippedType{name, "missing type information"})
+			continue
+		}
+		varName, err := dirs.name(pkg.Fset, directiveVar)
+		if err != nil {
+			return nil, nil, err
+		}
+		typeName, err := dirs.name(pkg.Fset, directiveType)
+		if err != nil {
+			return nil, nil, err
+		}
+		if dirs.has(directiveInstantiate) {
+			instances, err := instantiateType(pkg, imports, obj, dirs)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i := range instances {
+				instances[i].Var, instances[i].Type = varName, typeName
+			}
+			extpoints = append(extpoints, instances...)
+			continue
+		}
+		if reason := checkExtensionType(obj); reason != "" {
+			if dirs.has(directivePoint) {
+				return nil, nil, fmt.Errorf("%s: %s is marked as an extension point but is %s",
+					pkg.Fset.Position(typeSpec.Pos()), name, reason)
+			}
+			skipped = append(skipped, SkippedType{name, reason})
+			continue
+		}
+		if explicit && !dirs.has(directivePoint) {
+			skipped = append(skipped, SkippedType{name, "not marked with " + directivePrefix + directivePoint})
+			continue
+		}
+		extpoints = append(extpoints, ExtensionPoint{Name: name, Expr: name, Var: varName, Type: typeName})
+	}
+	return
+}
+
+// checkExtensionType describes why a declared type can't be used as an
+// extension type, or returns an empty string if it can.
+func checkExtensionType(obj types.Object) string {
+	if !obj.Exported() {
+		return "unexported"
+	}
+	typ := types.Unalias(obj.Type())
+	// an instance, such as the target of an alias to Codec[int], has the type
+	// parameters of its origin but is concrete
+	if named, ok := typ.(*types.Named); ok && named.TypeParams().Len() > 0 && named.TypeArgs().Len() == 0 {
+		return "generic, which needs an " + directivePrefix + directiveInstantiate + " directive"
+	}
+	return checkUnderlying(typ)
+}
+
+func checkUnderlying(typ types.Type) string {
+	switch typ := typ.Underlying().(type) {
+	case *types.Signature:
+		return ""
+	case *types.Interface:
+		if !typ.IsMethodSet() {
+			return "a constraint interface, which can't be used as a value"
+		}
+		return ""
+	}
+	return "neither an interface nor a func type"
+}
+
+// instantiateType returns an extension point for each instantiation of a
+// generic type requested by //extpoints:instantiate directives, which take
+// the form "<type> as <name>", for example "Codec[[]byte] as ByteCodec".
+func instantiateType(pkg *packages.Package, imports *importSet, obj types.Object, dirs directives) ([]ExtensionPoint, error) {
+	generic, ok := obj.Type().(*types.Named)
+	if !ok || generic.TypeParams().Len() == 0 {
+		return nil, fmt.Errorf("%s: %s can't be instantiated, it isn't generic", pkg.Fset.Position(obj.Pos()), obj.Name())
+	}
+	var extpoints []ExtensionPoint
+	for _, dir := range dirs {
+		if dir.Name != directiveInstantiate {
+			continue
+		}
+		pos := pkg.Fset.Position(dir.Pos)
+		args := strings.Join(dir.Args, " ")
+		i := strings.LastIndex(args, " as ")
+		if i < 0 {
+			return nil, fmt.Errorf("%s: expected %s%s <type> as <name>", pos, directivePrefix, directiveInstantiate)
+		}
+		expr, name := strings.TrimSpace(args[:i]), strings.TrimSpace(args[i+len(" as "):])
+		if !token.IsIdentifier(name) || !token.IsExported(name) {
+			return nil, fmt.Errorf("%s: %q is not an exported identifier", pos, name)
+		}
+		tv, err := types.Eval(pkg.Fset, pkg.Types, dir.Pos, expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", pos, err)
+		}
+		instance, ok := types.Unalias(tv.Type).(*types.Named)
+		if !tv.IsType() || !ok || instance.Origin() != generic {
+			return nil, fmt.Errorf("%s: %s is not an instance of %s", pos, expr, obj.Name())
+		}
+		if reason := checkUnderlying(instance); reason != "" {
+			return nil, fmt.Errorf("%s: %s is %s", pos, expr, reason)
+		}
+		extpoints = append(extpoints, ExtensionPoint{
+			Name: name,
+			Expr: types.TypeString(instance, imports.qualifier),
+		})
+	}
+	return extpoints, nil
+}
+
+// Import is a package imported by the generated code. Name is empty unless
+// it differs from the last element of Path.
+type Import struct {
+	Name string
+	Path string
+}
+
+// importSet collects the packages referred to by type expressions of
+// instantiated extension types. Packages whose names are taken, like
+// html/template and text/template together, get aliases.
+type importSet struct {
+	pkg   *types.Package
+	names map[string]string
+	// reserved are the identifiers declared by the template.
+	reserved []string
+}
+
+func (s *importSet) qualifier(pkg *types.Package) string {
+	if pkg == s.pkg {
+		return ""
+	}
+	if name, ok := s.names[pkg.Path()]; ok {
+		return name
+	}
+	name := pkg.Name()
+	// packages the template imports itself, like context, are shared
+	if !slices.Contains(s.reserved, pkg.Path()) {
+		for i := 2; s.taken(name); i++ {
+			name = pkg.Name() + strconv.Itoa(i)
+		}
+	}
+	s.names[pkg.Path()] = name
+	return name
+}
+
+// taken reports whether name is already declared in the generated code or
+// the package.
+func (s *importSet) taken(name string) bool {
+	if slices.Contains(s.reserved, name) || s.pkg.Scope().Lookup(name) != nil {
+		return true
+	}
+	for _, other := range s.names {
+		if other == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *importSet) list() []Import {
+	var imports []Import
+	for importPath, name := range s.names {
+		if path.Base(importPath) == name {
+			name = ""
+		}
+		imports = append(imports, Import{name, importPath})
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].Path < imports[j].Path
+	})
+	return imports
+}
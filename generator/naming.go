@@ -0,0 +1,171 @@
+package generator
+
+import (
+	"fmt"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gedex/inflector"
+)
+
+// Naming is a strategy for naming the variable of each extension point.
+type Naming string
+
+const (
+	// NamingPlural names variables by pluralizing the extension type,
+	// for example Subcommands. This is the default.
+	NamingPlural Naming = "plural"
+	// NamingPoint names variables by adding Point to the extension type,
+	// for example SubcommandPoint, which never needs pluralizing.
+	NamingPoint Naming = "point"
+)
+
+// Namings lists the supported naming strategies.
+var Namings = []Naming{NamingPlural, NamingPoint}
+
+func (n Naming) varName(name string) (string, error) {
+	switch n {
+	case NamingPlural, "":
+		return inflector.Pluralize(name), nil
+	case NamingPoint:
+		return name + "Point", nil
+	}
+	return "", fmt.Errorf("unknown naming strategy %q", string(n))
+}
+
+// typeName returns the name of an extension point's wrapper type.
+func typeName(name string) string {
+	return lowerInitial(name) + "Ext"
+}
+
+// lowerInitial lowercases the leading uppercase letters of name, treating
+// an initial acronym as one word: HTTPHandler becomes httpHandler.
+func lowerInitial(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) && unicode.IsLetter(runes[upper]) {
+		// the last uppercase letter starts the next word
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// applyNaming names the variable and wrapper type of extension points
+// that weren't named by directives.
+func applyNaming(extpoints []ExtensionPoint, naming Naming) error {
+	for i := range extpoints {
+		ep := &extpoints[i]
+		if ep.Var == "" {
+			name, err := naming.varName(ep.Name)
+			if err != nil {
+				return err
+			}
+			ep.Var = name
+		}
+		if ep.Type == "" {
+			ep.Type = typeName(ep.Name)
+		}
+	}
+	return nil
+}
+
+// The package level identifiers and imports declared by the templates
+// besides those of each extension point.
+var (
+	runtimeIdents = []string{
+		"extRegistry", "RegisterExtension", "TryRegisterExtension",
+		"MustRegisterExtension", "UnregisterExtension", "StartExtensions",
+		"StopExtensions", "ExtensionRegistry", "ExtensionPoints", "NewExtensionPoints",
+		"context", "extpoints",
+	}
+	selfContainedIdents = []string{
+		"extRegistry", "registryType", "extensionTypes", "RegisterExtension",
+		"UnregisterExtension", "extensionPoint", "newExtensionPoint", "extensionName",
+		"reflect", "runtime", "sort", "strings", "sync",
+	}
+)
+
+// NameCollision is a generated identifier that is declared more than once.
+type NameCollision struct {
+	Name string
+	// Decls describes each declaration of Name.
+	Decls []string
+}
+
+// NameCollisionError is returned when identifiers of the generated code
+// collide with each other or with those already declared in the package.
+type NameCollisionError struct {
+	Collisions []NameCollision
+}
+
+func (e *NameCollisionError) Error() string {
+	var lines []string
+	for _, c := range e.Collisions {
+		lines = append(lines, fmt.Sprintf("%s: %s", c.Name, strings.Join(c.Decls, ", ")))
+	}
+	return "generated identifiers collide, rename them with " + directivePrefix + directiveVar +
+		" or " + directivePrefix + directiveType + ":\n\t" + strings.Join(lines, "\n\t")
+}
+
+// withoutTemplateImports returns imports without the packages the template
+// imports itself.
+func withoutTemplateImports(templateIdents []string, imports []Import) []Import {
+	var filtered []Import
+	for _, imp := range imports {
+		if imp.Name == "" && slices.Contains(templateIdents, imp.Path) {
+			continue
+		}
+		filtered = append(filtered, imp)
+	}
+	return filtered
+}
+
+// checkCollisions makes sure each identifier declared by the generated code
+// is unique within the package.
+func checkCollisions(pkg *types.Package, templateIdents []string, imports []Import, extpoints []ExtensionPoint) error {
+	decls := make(map[string][]string)
+	for _, name := range templateIdents {
+		decls[name] = append(decls[name], "generated code")
+	}
+	for _, imp := range imports {
+		if imp.Name == "" && decls[imp.Path] != nil {
+			// already imported by the template
+			continue
+		}
+		name := imp.Name
+		if name == "" {
+			name = imp.Path[strings.LastIndex(imp.Path, "/")+1:]
+		}
+		decls[name] = append(decls[name], "import of "+imp.Path)
+	}
+	for _, ep := range extpoints {
+		decls[ep.Var] = append(decls[ep.Var], "var of "+ep.Name)
+		decls[ep.Type] = append(decls[ep.Type], "type of "+ep.Name)
+	}
+
+	var collisions []NameCollision
+	for name, uses := range decls {
+		if obj := pkg.Scope().Lookup(name); obj != nil {
+			uses = append([]string{"declared in package"}, uses...)
+		}
+		if len(uses) > 1 {
+			collisions = append(collisions, NameCollision{name, uses})
+		}
+	}
+	if len(collisions) == 0 {
+		return nil
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Name < collisions[j].Name
+	})
+	return &NameCollisionError{collisions}
+}
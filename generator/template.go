@@ -0,0 +1,328 @@
+package generator
+
+// runtimeTemplate generates typed extension points backed by the
+// extpoints runtime package.
+var runtimeTemplate = `// generated by go-extpoints -- DO NOT EDIT
+package {{.Package}}
+
+import (
+	"context"
+
+	"` + RuntimePackage + `"
+{{range .Imports}}
+	{{.Name}} "{{.Path}}"{{end}}
+)
+
+{{if .DefaultRegistry}}var extRegistry = extpoints.DefaultRegistry{{else}}var extRegistry = extpoints.NewRegistry(){{end}}
+
+// Top level registration
+
+func RegisterExtension(extension interface{}, name string, opts ...extpoints.Option) []string {
+	return extRegistry.RegisterExtension(extension, name, opts...)
+}
+
+func TryRegisterExtension(extension interface{}, name string, opts ...extpoints.Option) ([]string, error) {
+	return extRegistry.TryRegisterExtension(extension, name, opts...)
+}
+
+func MustRegisterExtension(extension interface{}, name string, opts ...extpoints.Option) []string {
+	return extRegistry.MustRegisterExtension(extension, name, opts...)
+}
+
+func UnregisterExtension(name string) []string {
+	return extRegistry.UnregisterExtension(name)
+}
+
+func StartExtensions(ctx context.Context) error {
+	return extRegistry.Start(ctx)
+}
+
+func StopExtensions(ctx context.Context) error {
+	return extRegistry.Stop(ctx)
+}
+
+// ExtensionRegistry returns the registry of the extension points, for
+// watching them all, sealing them or taking a snapshot of them.
+func ExtensionRegistry() *extpoints.Registry {
+	return extRegistry
+}
+
+// ExtensionPoints is a set of the extension points with a registry of its
+// own, isolated from the package level ones, for tests and other uses
+// needing their own sets of extensions.
+type ExtensionPoints struct {
+	Registry *extpoints.Registry{{range .ExtensionPoints}}
+	{{.Var}} *{{.Type}}{{end}}
+}
+
+// NewExtensionPoints returns a new set of the extension points with an
+// empty registry.
+func NewExtensionPoints() *ExtensionPoints {
+	r := extpoints.NewRegistry()
+	return &ExtensionPoints{
+		Registry: r,{{range .ExtensionPoints}}
+		{{.Var}}: &{{.Type}}{extpoints.NewPoint[{{.Expr}}](r, "{{.Name}}", "{{.Var}}")},{{end}}
+	}
+}
+
+{{range .ExtensionPoints}}// {{.Name}}
+
+var {{.Var}} = &{{.Type}}{
+	extpoints.NewPoint[{{.Expr}}](extRegistry, "{{.Name}}", "{{.Var}}"),
+}
+
+type {{.Type}} struct {
+	*extpoints.Point[{{.Expr}}]
+}
+
+{{end}}`
+
+// selfContainedTemplate generates extension points that don't depend on
+// any other package, as go-extpoints did before the runtime package.
+var selfContainedTemplate = `// generated by go-extpoints -- DO NOT EDIT
+package {{.Package}}
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+{{range .Imports}}
+	{{.Name}} "{{.Path}}"{{end}}
+)
+
+var extRegistry = &registryType{m: make(map[string]*extensionPoint)}
+
+type registryType struct {
+	sync.Mutex
+	m     map[string]*extensionPoint
+	order []string
+}
+
+// Top level registration
+
+func extensionTypes(extension interface{}) []string {
+	var ifaces []string
+	typ := reflect.TypeOf(extension)
+	for _, name := range extRegistry.order {
+		ep := extRegistry.m[name]
+		if ep.iface.Kind() == reflect.Func && typ.AssignableTo(ep.iface) {
+			ifaces = append(ifaces, name)
+		}
+		if ep.iface.Kind() != reflect.Func && typ.Implements(ep.iface) {
+			ifaces = append(ifaces, name)
+		}
+	}
+	return ifaces
+}
+
+func RegisterExtension(extension interface{}, name string) []string {
+	extRegistry.Lock()
+	defer extRegistry.Unlock()
+	var ifaces []string
+	for _, iface := range extensionTypes(extension) {
+		if extRegistry.m[iface].register(extension, name) {
+			ifaces = append(ifaces, iface)
+		}
+	}
+	return ifaces
+}
+
+func UnregisterExtension(name string) []string {
+	extRegistry.Lock()
+	defer extRegistry.Unlock()
+	var ifaces []string
+	for _, iface := range extRegistry.order {
+		if extRegistry.m[iface].unregister(name) {
+			ifaces = append(ifaces, iface)
+		}
+	}
+	return ifaces
+}
+
+
+// Base extension point
+
+type extensionPoint struct {
+	sync.Mutex
+	iface      reflect.Type
+	extensions map[string]interface{}
+	order      []string
+}
+
+func newExtensionPoint(iface interface{}, name string) *extensionPoint {
+	ep := &extensionPoint{
+		iface:      reflect.TypeOf(iface).Elem(),
+		extensions: make(map[string]interface{}),
+	}
+	extRegistry.Lock()
+	extRegistry.m[name] = ep
+	extRegistry.order = append(extRegistry.order, name)
+	extRegistry.Unlock()
+	return ep
+}
+
+func (ep *extensionPoint) lookup(name string) interface{} {
+	ep.Lock()
+	defer ep.Unlock()
+	ext, ok := ep.extensions[name]
+	if !ok {
+		return nil
+	}
+	return ext
+}
+
+func (ep *extensionPoint) all() map[string]interface{} {
+	ep.Lock()
+	defer ep.Unlock()
+	all := make(map[string]interface{})
+	for k, v := range ep.extensions {
+		all[k] = v
+	}
+	return all
+}
+
+func (ep *extensionPoint) ordered() []interface{} {
+	ep.Lock()
+	defer ep.Unlock()
+	var ordered []interface{}
+	for _, name := range ep.order {
+		ordered = append(ordered, ep.extensions[name])
+	}
+	return ordered
+}
+
+func (ep *extensionPoint) names() []string {
+	ep.Lock()
+	defer ep.Unlock()
+	names := append([]string(nil), ep.order...)
+	sort.Strings(names)
+	return names
+}
+
+func (ep *extensionPoint) register(extension interface{}, name string) bool {
+	ep.Lock()
+	defer ep.Unlock()
+	if name == "" {
+		name = extensionName(extension)
+	}
+	_, exists := ep.extensions[name]
+	if exists || name == "" {
+		return false
+	}
+	ep.extensions[name] = extension
+	ep.order = append(ep.order, name)
+	return true
+}
+
+// extensionName names an extension registered without a name after its
+// ExtensionName method, its func or method, or its type. Closures without
+// a named func type have no name.
+func extensionName(extension interface{}) string {
+	if namer, ok := extension.(interface{ ExtensionName() string }); ok {
+		return namer.ExtensionName()
+	}
+	typ := reflect.TypeOf(extension)
+	if typ == nil {
+		return ""
+	}
+	if typ.Kind() == reflect.Func {
+		name := strings.TrimSuffix(runtime.FuncForPC(
+			reflect.ValueOf(extension).Pointer()).Name(), "-fm")
+		name = strings.ReplaceAll(name[strings.LastIndex(name, "/")+1:], "[...]", "")
+		nameParts := strings.Split(name, ".")
+		closure := false
+		for _, part := range nameParts[1:] {
+			digits := strings.TrimPrefix(part, "func")
+			if part == "" || digits != "" && strings.Trim(digits, "0123456789") == "" {
+				closure = true
+			}
+		}
+		if !closure {
+			return nameParts[len(nameParts)-1]
+		}
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	name := typ.Name()
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func (ep *extensionPoint) unregister(name string) bool {
+	ep.Lock()
+	defer ep.Unlock()
+	_, exists := ep.extensions[name]
+	if !exists {
+		return false
+	}
+	delete(ep.extensions, name)
+	for i, n := range ep.order {
+		if n == name {
+			ep.order = append(ep.order[:i], ep.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+{{range .ExtensionPoints}}// {{.Name}}
+
+var {{.Var}} = &{{.Type}}{
+	newExtensionPoint(new({{.Expr}}), "{{.Name}}"),
+}
+
+type {{.Type}} struct {
+	*extensionPoint
+}
+
+func (ep *{{.Type}}) Unregister(name string) bool {
+	return ep.unregister(name)
+}
+
+func (ep *{{.Type}}) Register(extension {{.Expr}}, name string) bool {
+	return ep.register(extension, name)
+}
+
+func (ep *{{.Type}}) Lookup(name string) {{.Expr}} {
+	ext := ep.lookup(name)
+	if ext == nil {
+		return nil
+	}
+	return ext.({{.Expr}})
+}
+
+func (ep *{{.Type}}) Select(names []string) []{{.Expr}} {
+	var selected []{{.Expr}}
+	for _, name := range names {
+		selected = append(selected, ep.Lookup(name))
+	}
+	return selected
+}
+
+func (ep *{{.Type}}) All() map[string]{{.Expr}} {
+	all := make(map[string]{{.Expr}})
+	for k, v := range ep.all() {
+		all[k] = v.({{.Expr}})
+	}
+	return all
+}
+
+func (ep *{{.Type}}) Ordered() []{{.Expr}} {
+	var ordered []{{.Expr}}
+	for _, v := range ep.ordered() {
+		ordered = append(ordered, v.({{.Expr}}))
+	}
+	return ordered
+}
+
+func (ep *{{.Type}}) Names() []string {
+	return ep.names()
+}
+
+
+{{end}}`
@@ -0,0 +1,5 @@
+package broken
+
+type Handler interface {
+	Handle(
+}
@@ -0,0 +1,5 @@
+package external
+
+type Handler interface {
+	Handle()
+}
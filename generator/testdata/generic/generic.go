@@ -0,0 +1,29 @@
+package generic
+
+import (
+	"context"
+	ht "html/template"
+	"net/http"
+	tt "text/template"
+)
+
+//extpoints:instantiate Codec[[]byte] as ByteCodec
+//extpoints:instantiate Codec[*http.Request] as RequestCodec
+//extpoints:instantiate Codec[context.Context] as ContextCodec
+//extpoints:instantiate Codec[*ht.Template] as HTMLCodec
+//extpoints:instantiate Codec[*tt.Template] as TextCodec
+type Codec[T any] interface {
+	Encode(ctx context.Context, value T) ([]byte, error)
+}
+
+type IntCodec = Codec[int]
+
+type Mapper[K comparable, V any] func(key K) V
+
+type Middleware func(next http.Handler) http.Handler
+
+// the templates are only referred to by directives
+var (
+	_ *ht.Template
+	_ *tt.Template
+)
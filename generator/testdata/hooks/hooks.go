@@ -0,0 +1,26 @@
+package hooks
+
+import "io"
+
+type Closer = io.Closer
+
+type Handler interface {
+	Handle(event Event, req *Request)
+}
+
+type helper interface {
+	help()
+}
+
+//extpoints:ignore
+type Event interface {
+	Name() string
+}
+
+type (
+	Starter interface{ Start() error }
+	Stopper interface{ Stop() }
+	Hook    func(event Event)
+)
+
+type Options struct{}
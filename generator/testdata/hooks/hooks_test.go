@@ -0,0 +1,3 @@
+package hooks
+
+type TestHook func()
@@ -0,0 +1,9 @@
+package hooks
+
+// Request is used by Handler, but types in _ext.go files aren't extension
+// types.
+type Request struct{}
+
+type Legacy interface {
+	Run()
+}
@@ -0,0 +1,12 @@
+package naming
+
+type HTTPHandler interface {
+	Handle(path string)
+}
+
+//extpoints:var StatusChecks
+type Status interface {
+	Check() error
+}
+
+type Series func() []float64
@@ -1,179 +1,121 @@
 package main
 
 import (
-	"go/ast"
-	"go/parser"
-	"go/token"
-	"io/ioutil"
+	"flag"
+	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"strings"
-	"text/template"
 
-	"github.com/gedex/inflector"
+	"github.com/progrium/go-extpoints/generator"
 )
 
-func processFile(inputPath string) (string, []string) {
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, inputPath, nil, parser.ParseComments)
-	if err != nil {
-		log.Fatalf("Could not parse file: %s", err)
-	}
-
-	packageName := identifyPackage(f)
-	if packageName == "" {
-		log.Fatalf("Could not determine package name of %s", inputPath)
-	}
-
-	var ifaces []string
-	for _, decl := range f.Decls {
-		if typeName, ok := identifyInterface(decl); ok {
-			ifaces = append(ifaces, typeName)
-			continue
-		}
-		if typeName, ok := identifyFuncType(decl); ok {
-			ifaces = append(ifaces, typeName)
-			continue
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
 		}
 	}
-
-	return packageName, ifaces
+	return list
 }
 
-func identifyPackage(f *ast.File) string {
-	if f.Name == nil {
-		return ""
+func namings() string {
+	var names []string
+	for _, naming := range generator.Namings {
+		names = append(names, string(naming))
 	}
-	return f.Name.Name
-}
-
-func identifyFuncType(decl ast.Decl) (typeName string, match bool) {
-	genDecl, ok := decl.(*ast.GenDecl)
-	if !ok {
-		return
-	}
-	for _, spec := range genDecl.Specs {
-		if typeSpec, ok := spec.(*ast.TypeSpec); ok {
-			if _, ok := typeSpec.Type.(*ast.FuncType); ok {
-				if typeSpec.Name != nil {
-					typeName = typeSpec.Name.Name
-					break
-				}
-			}
-		}
-	}
-	if typeName == "" {
-		return
-	}
-	match = true
-	return
-}
-
-func identifyInterface(decl ast.Decl) (typeName string, match bool) {
-	genDecl, ok := decl.(*ast.GenDecl)
-	if !ok {
-		return
-	}
-	for _, spec := range genDecl.Specs {
-		if typeSpec, ok := spec.(*ast.TypeSpec); ok {
-			if _, ok := typeSpec.Type.(*ast.InterfaceType); ok {
-				if typeSpec.Name != nil {
-					typeName = typeSpec.Name.Name
-					break
-				}
-			}
-		}
-	}
-	if typeName == "" {
-		return
-	}
-	match = true
-	return
-}
-
-type extensionPoint struct {
-	Name string
+	return strings.Join(names, ", ")
 }
 
-func (i *extensionPoint) Var() string {
-	return inflector.Pluralize(i.Name)
-}
-
-func (i *extensionPoint) Type() string {
-	return strings.ToLower(i.Name[0:1]) + i.Name[1:] + "Ext"
-}
-
-func extensionPoints(ifaces []string) []extensionPoint {
-	var extpoints []extensionPoint
-	for _, iface := range ifaces {
-		extpoints = append(extpoints, extensionPoint{iface})
-	}
-	return extpoints
-}
-
-type templateData struct {
-	Package         string
-	ExtensionPoints []extensionPoint
-}
-
-func renderExtpoints(path, packageName string, ifaces []string) error {
-	output, err := os.Create(path)
-	if err != nil {
-		log.Fatalf("Could not open output file: %s", err)
-	}
-	defer output.Close()
-	outputTemplate := template.Must(template.New("render").Parse(extpointsTemplate))
-	return outputTemplate.Execute(output, templateData{packageName, extensionPoints(ifaces)})
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: go-extpoints [flags] [package dir] [types...]\n\n")
+	fmt.Fprintf(os.Stderr, "Generates extension points for the interface and func types in\n")
+	fmt.Fprintf(os.Stderr, "the package dir, which defaults to ./extpoints. Any types listed\n")
+	fmt.Fprintf(os.Stderr, "after the package dir are added to -types.\n\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
 }
 
 func main() {
 	log.SetFlags(0)
 	log.SetPrefix("extpoints: ")
 
-	packagePath := "./extpoints"
-	if len(os.Args) > 1 {
-		packagePath = os.Args[1]
+	var (
+		output          = flag.String("o", "", "output file (default \"<package dir>/extpoints.go\")")
+		pkgName         = flag.String("pkg", "", "package name of the output file (default is the loaded package's name)")
+		typeList        = flag.String("types", "", "comma-separated list of types to generate (default all)")
+		exclude         = flag.String("exclude", "", "comma-separated list of types not to generate")
+		tags            = flag.String("tags", "", "comma-separated list of build tags to apply when loading the package")
+		tests           = flag.Bool("tests", false, "also load _test.go files, for generating into a _test.go file")
+		explicit        = flag.Bool("explicit", false, "only generate types marked with //extpoints:point")
+		selfContained   = flag.Bool("self-contained", false, "generate code with its own registry instead of importing "+generator.RuntimePackage)
+		defaultRegistry = flag.Bool("default-registry", false, "add the extension points to the runtime package's DefaultRegistry")
+		naming          = flag.String("naming", string(generator.NamingPlural), "naming strategy for extension point variables: "+namings())
+		dryRun          = flag.Bool("dry-run", false, "print the extension points that would be generated, without writing")
+		toStdout        = flag.Bool("stdout", false, "write the generated code to stdout instead of the output file")
+		check           = flag.Bool("check", false, "exit with a diff instead of writing if the output file is out of date")
+	)
+	flag.Usage = usage
+	flag.Parse()
+
+	cfg := generator.Config{
+		Dir:             "./extpoints",
+		Output:          *output,
+		Package:         *pkgName,
+		Types:           splitList(*typeList),
+		Exclude:         splitList(*exclude),
+		Tags:            splitList(*tags),
+		Tests:           *tests,
+		Explicit:        *explicit,
+		Naming:          generator.Naming(*naming),
+		SelfContained:   *selfContained,
+		DefaultRegistry: *defaultRegistry,
+		DryRun:          *dryRun || *toStdout || *check,
+	}
+	if flag.NArg() > 0 {
+		cfg.Dir = flag.Arg(0)
+	}
+	if flag.NArg() > 1 {
+		cfg.Types = append(cfg.Types, flag.Args()[1:]...)
+	}
+
+	log.Printf("Loading package %s", cfg.Dir)
+	result, err := generator.Generate(cfg)
+	for _, skip := range result.Skipped {
+		log.Printf("Skipping %s: %s", skip.Name, skip.Reason)
 	}
-	if _, err := os.Stat(packagePath); os.IsNotExist(err) {
-		log.Fatal("Unable to find package for extpoints, not found:", packagePath)
+	if err != nil {
+		log.Fatal(err)
 	}
-
-	var packageName string
-	var ifaces, ifacesAll []string
-	ifacesAllowed := make(map[string]struct{})
-
-	if len(os.Args) > 2 {
-		for _, iface := range os.Args[2:] {
-			ifacesAllowed[iface] = struct{}{}
-		}
+	var names []string
+	for _, ep := range result.ExtensionPoints {
+		names = append(names, ep.Name)
 	}
+	log.Printf("Found interfaces: %#v", names)
 
-	files, _ := ioutil.ReadDir(packagePath)
-	for _, file := range files {
-		if file.Name() != "extpoints.go" && !strings.HasSuffix(file.Name(), "_ext.go") {
-			path := filepath.Join(packagePath, file.Name())
-			log.Printf("Processing file %s", path)
-			packageName, ifaces = processFile(path)
-			if len(ifacesAllowed) > 0 {
-				var ifacesFiltered []string
-				for _, iface := range ifaces {
-					_, allowed := ifacesAllowed[iface]
-					if allowed {
-						ifacesFiltered = append(ifacesFiltered, iface)
-					}
-				}
-				ifaces = ifacesFiltered
-			}
-			log.Printf("Found interfaces: %#v", ifaces)
-			ifacesAll = append(ifacesAll, ifaces...)
+	switch {
+	case *check:
+		existing, err := os.ReadFile(result.Output)
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatalf("Could not read %s: %s", result.Output, err)
 		}
-	}
-
-	path := filepath.Join(packagePath, "extpoints.go")
-	log.Printf("Writing file %s", path)
-	err := renderExtpoints(path, packageName, ifacesAll)
-	if err != nil {
-		log.Fatalf("Could not write extpoints.go file: %s", err)
+		if diff := unifiedDiff(result.Output, result.Output+" (generated)", existing, result.Source); diff != nil {
+			os.Stdout.Write(diff)
+			log.Fatalf("%s is out of date, run go generate", result.Output)
+		}
+		log.Printf("%s is up to date", result.Output)
+	case *toStdout:
+		os.Stdout.Write(result.Source)
+	case *dryRun:
+		fmt.Printf("package %s (%s)\n", result.Package, result.Output)
+		for _, ep := range result.ExtensionPoints {
+			fmt.Printf("\t%s: var %s *%s\n", ep.Name, ep.Var, ep.Type)
+		}
+	case result.Written:
+		log.Printf("Wrote file %s", result.Output)
+	default:
+		log.Printf("File %s is unchanged", result.Output)
 	}
 }
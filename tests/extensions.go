@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/hex"
 	"strings"
 
 	"github.com/progrium/go-extpoints/tests/extpoints"
@@ -10,7 +11,9 @@ func init() {
 	extpoints.RegisterExtension(new(noop), "noop")                  // Noop
 	extpoints.RegisterExtension(new(noop2), "noop2")                // Noop
 	extpoints.RegisterExtension(new(uppercaseTransformer), "upper") // StringTransformer
+	extpoints.RegisterExtension(new(noopTransformer), "identity")   // Noop, StringTransformer
 	extpoints.NoopFactories.Register(noopFactory, "")
+	extpoints.ByteCodecs.Register(new(hexCodec), "hex")
 }
 
 func noopFactory() extpoints.Noop {
@@ -34,3 +37,19 @@ type uppercaseTransformer struct{}
 func (t *uppercaseTransformer) Transform(input string) string {
 	return strings.ToUpper(input)
 }
+
+type hexCodec struct{}
+
+func (c *hexCodec) Encode(value []byte) string {
+	return hex.EncodeToString(value)
+}
+
+type noopTransformer struct{}
+
+func (t *noopTransformer) Noop() string {
+	return "identity"
+}
+
+func (t *noopTransformer) Transform(input string) string {
+	return input
+}
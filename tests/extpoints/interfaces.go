@@ -9,3 +9,8 @@ type StringTransformer interface {
 }
 
 type NoopFactory func() Noop
+
+//extpoints:instantiate Codec[[]byte] as ByteCodec
+type Codec[T any] interface {
+	Encode(value T) string
+}
@@ -9,6 +9,7 @@ import (
 var noops = extpoints.Noops
 var noopFactories = extpoints.NoopFactories
 var transformers = extpoints.StringTransformers
+var byteCodecs = extpoints.ByteCodecs
 
 func TestLookupSuccess(t *testing.T) {
 	ext := noops.Lookup("noop")
@@ -37,6 +38,36 @@ func TestSelect(t *testing.T) {
 	}
 }
 
+func TestNamesSorted(t *testing.T) {
+	names := transformers.Names()
+	if len(names) != 2 || names[0] != "identity" || names[1] != "upper" {
+		t.Fatalf("Names not sorted: %v", names)
+	}
+}
+
+func TestOrdered(t *testing.T) {
+	exts := noops.Ordered()
+	if len(exts) != 3 {
+		t.Fatal("Ordered returned wrong number of elements")
+	}
+	if exts[0].Noop() != "noop" || exts[1].Noop() != "noop2" || exts[2].Noop() != "identity" {
+		t.Fatal("Ordered elements not in registration order")
+	}
+}
+
+func TestRegisterExtensionOrder(t *testing.T) {
+	ifaces := extpoints.UnregisterExtension("identity")
+	defer extpoints.RegisterExtension(new(noopTransformer), "identity")
+	if len(ifaces) != 2 || ifaces[0] != "Noop" || ifaces[1] != "StringTransformer" {
+		t.Fatalf("UnregisterExtension not in declaration order: %v", ifaces)
+	}
+	ifaces = extpoints.RegisterExtension(new(noopTransformer), "identity2")
+	defer extpoints.UnregisterExtension("identity2")
+	if len(ifaces) != 2 || ifaces[0] != "Noop" || ifaces[1] != "StringTransformer" {
+		t.Fatalf("RegisterExtension not in declaration order: %v", ifaces)
+	}
+}
+
 func TestUsingExtension(t *testing.T) {
 	upper := transformers.Lookup("upper")
 	if upper.Transform("string") != "STRING" {
@@ -51,3 +82,49 @@ func TestUsingFuncComponent(t *testing.T) {
 		t.Fatal("Used extension, but didn't work as expected")
 	}
 }
+
+func TestUsingGenericExtension(t *testing.T) {
+	codec := byteCodecs.Lookup("hex")
+	if codec == nil {
+		t.Fatal("Lookup returned not ok for registered extension")
+	}
+	if codec.Encode([]byte("hi")) != "6869" {
+		t.Fatal("Used extension, but didn't work as expected")
+	}
+}
+
+type valueNoop struct{}
+
+func (n valueNoop) Noop() string {
+	return "value"
+}
+
+func (n valueNoop) NewNoop() extpoints.Noop {
+	return n
+}
+
+func TestDefaultNames(t *testing.T) {
+	if ifaces := extpoints.RegisterExtension(valueNoop{}, ""); len(ifaces) != 1 {
+		t.Fatalf("RegisterExtension failed for value: %v", ifaces)
+	}
+	defer extpoints.UnregisterExtension("valueNoop")
+	if noops.Lookup("valueNoop") == nil {
+		t.Fatal("value not registered under its type name")
+	}
+
+	if !noopFactories.Register(valueNoop{}.NewNoop, "") {
+		t.Fatal("Register failed for method value")
+	}
+	defer noopFactories.Unregister("NewNoop")
+	if noopFactories.Lookup("NewNoop") == nil {
+		t.Fatal("method value not registered under its method name")
+	}
+
+	if !noopFactories.Register(func() extpoints.Noop { return nil }, "") {
+		t.Fatal("Register failed for closure")
+	}
+	defer noopFactories.Unregister("NoopFactory")
+	if noopFactories.Lookup("NoopFactory") == nil {
+		t.Fatal("closure not registered under its func type name")
+	}
+}